@@ -18,6 +18,7 @@ package madmin
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -47,6 +48,10 @@ type ErrorResponse struct {
 	// Region where the bucket is located. This header is returned
 	// only in HEAD bucket and ListObjects response.
 	Region string
+
+	// StatusCode is the HTTP status code of the response, only set
+	// when the error was generated from an HTTP response.
+	StatusCode int `xml:"-" json:"-"`
 }
 
 // Error - Returns HTTP error string
@@ -70,14 +75,54 @@ func httpRespToErrorResponse(resp *http.Response) error {
 	err := jsonDecoder(resp.Body, &errResp)
 	if err != nil {
 		return ErrorResponse{
-			Code:    resp.Status,
-			Message: fmt.Sprintf("Failed to parse server response: %s.", err),
+			Code:       resp.Status,
+			Message:    fmt.Sprintf("Failed to parse server response: %s.", err),
+			StatusCode: resp.StatusCode,
 		}
 	}
 	closeResponse(resp)
+	errResp.StatusCode = resp.StatusCode
 	return errResp
 }
 
+// List of error codes returned when the request could not be
+// authenticated.
+var authenticationErrCodes = map[string]struct{}{
+	"InvalidAccessKeyId":          {},
+	"SignatureDoesNotMatch":       {},
+	"ExpiredToken":                {},
+	"InvalidToken":                {},
+	"InvalidClientTokenId":        {},
+	"XMinioInvalidIAMCredentials": {},
+}
+
+// IsAuthenticationError - returns true if err indicates that the
+// credentials used for the request are invalid (HTTP 401).
+func IsAuthenticationError(err error) bool {
+	var errResp ErrorResponse
+	if !errors.As(err, &errResp) {
+		return false
+	}
+	if errResp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	_, ok := authenticationErrCodes[errResp.Code]
+	return ok
+}
+
+// IsAccessDenied - returns true if err indicates that the credentials
+// are valid but not permitted to perform the request (HTTP 403).
+func IsAccessDenied(err error) bool {
+	var errResp ErrorResponse
+	if !errors.As(err, &errResp) {
+		return false
+	}
+	if IsAuthenticationError(errResp) {
+		return false
+	}
+	return errResp.StatusCode == http.StatusForbidden || errResp.Code == "AccessDenied"
+}
+
 // ToErrorResponse - Returns parsed ErrorResponse struct from body and
 // http headers.
 //
@@ -100,6 +145,10 @@ func ToErrorResponse(err error) ErrorResponse {
 	}
 }
 
+// ErrEmptyResponse is returned when the server, or a proxy in front of
+// it, responds with a success status but without a body.
+var ErrEmptyResponse = errors.New("empty response body received from the server")
+
 // ErrInvalidArgument - Invalid argument response.
 func ErrInvalidArgument(message string) error {
 	return ErrorResponse{
@@ -108,3 +157,45 @@ func ErrInvalidArgument(message string) error {
 		RequestID: "minio",
 	}
 }
+
+// ErrUnexpectedResponse - Unexpected response from the server.
+func ErrUnexpectedResponse(message string) error {
+	return ErrorResponse{
+		Code:      "UnexpectedResponse",
+		Message:   message,
+		RequestID: "minio",
+	}
+}
+
+// NotSupportedError - returned when the server does not implement the
+// requested admin API, usually because it is running an older release.
+type NotSupportedError struct {
+	API string
+}
+
+// Error - Returns the error string
+func (e NotSupportedError) Error() string {
+	return e.API + " is not supported by the server"
+}
+
+// List of error codes returned by servers for admin APIs they don't
+// implement.
+var notSupportedErrCodes = map[string]struct{}{
+	"NotImplemented":          {},
+	"XMinioUnknownAPIRequest": {},
+	"XMinioAdminAPINotFound":  {},
+}
+
+// toNotSupportedError converts err to a NotSupportedError for api if
+// it reports an unimplemented API, err is returned otherwise.
+func toNotSupportedError(api string, err error) error {
+	errResp := ToErrorResponse(err)
+	switch errResp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return NotSupportedError{API: api}
+	}
+	if _, ok := notSupportedErrCodes[errResp.Code]; ok {
+		return NotSupportedError{API: api}
+	}
+	return err
+}
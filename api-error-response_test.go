@@ -0,0 +1,61 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// Tests classification of 401 and 403 responses.
+func TestAuthErrors(t *testing.T) {
+	testCases := []struct {
+		status       int
+		code         string
+		authErr      bool
+		accessDenied bool
+	}{
+		{http.StatusUnauthorized, "Unauthorized", true, false},
+		{http.StatusForbidden, "AccessDenied", false, true},
+		{http.StatusForbidden, "InvalidAccessKeyId", true, false},
+		{http.StatusBadRequest, "InvalidArgument", false, false},
+	}
+
+	for i, testCase := range testCases {
+		adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(testCase.status)
+			w.Write([]byte(`{"Code":"` + testCase.code + `","Message":"error"}`))
+		})
+
+		_, _, err := adm.Heal(context.Background(), "bucket", "", HealOpts{}, "", false, false)
+		if IsAuthenticationError(err) != testCase.authErr {
+			t.Errorf("Test %d: Heal expected authentication error %v, got %v", i+1, testCase.authErr, err)
+		}
+		if IsAccessDenied(err) != testCase.accessDenied {
+			t.Errorf("Test %d: Heal expected access denied %v, got %v", i+1, testCase.accessDenied, err)
+		}
+
+		_, err = adm.BackgroundHealStatus(context.Background())
+		if IsAuthenticationError(err) != testCase.authErr {
+			t.Errorf("Test %d: BackgroundHealStatus expected authentication error %v, got %v", i+1, testCase.authErr, err)
+		}
+		if IsAccessDenied(err) != testCase.accessDenied {
+			t.Errorf("Test %d: BackgroundHealStatus expected access denied %v, got %v", i+1, testCase.accessDenied, err)
+		}
+	}
+}
@@ -70,6 +70,15 @@ type AdminClient struct {
 	// Advanced functionality.
 	isTraceEnabled bool
 	traceOutput    io.Writer
+
+	// Reject unknown fields in decoded responses.
+	strictDecoding bool
+
+	// Optional cache of the background heal status.
+	healStatusCache *healStatusCache
+
+	// Clock of the time based helpers, time.Now when nil.
+	clock func() time.Time
 }
 
 // Global constants.
@@ -78,6 +87,9 @@ const (
 	libraryVersion = "0.0.1"
 
 	libraryAdminURLPrefix = "/minio/admin"
+
+	// Endpoints with this prefix are unix socket paths.
+	unixSocketPrefix = "unix://"
 )
 
 // User Agent should always following the below style.
@@ -96,7 +108,8 @@ type Options struct {
 	// Add future fields here
 }
 
-// New - instantiate minio admin client
+// New - instantiate minio admin client, endpoint is either a
+// host[:port] or a unix socket path prefixed with "unix://".
 func New(endpoint string, accessKeyID, secretAccessKey string, secure bool) (*AdminClient, error) {
 	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
 
@@ -124,10 +137,27 @@ func privateNew(endpoint string, creds *credentials.Credentials, secure bool) (*
 		return nil, err
 	}
 
-	// construct endpoint.
-	endpointURL, err := getEndpointURL(endpoint, secure)
-	if err != nil {
-		return nil, err
+	var (
+		endpointURL *url.URL
+		transport   http.RoundTripper
+	)
+	if strings.HasPrefix(endpoint, unixSocketPrefix) {
+		// Requests are sent over the unix socket, the host
+		// is only used to sign requests.
+		socketPath := strings.TrimPrefix(endpoint, unixSocketPrefix)
+		if socketPath == "" {
+			return nil, ErrInvalidArgument("Endpoint: " + endpoint + " is missing the unix socket path.")
+		}
+		endpointURL = &url.URL{Scheme: "http", Host: "localhost"}
+		transport = unixSocketTransport(socketPath)
+		secure = false
+	} else {
+		// construct endpoint.
+		endpointURL, err = getEndpointURL(endpoint, secure)
+		if err != nil {
+			return nil, err
+		}
+		transport = DefaultTransport(secure)
 	}
 
 	clnt := new(AdminClient)
@@ -144,12 +174,14 @@ func privateNew(endpoint string, creds *credentials.Credentials, secure bool) (*
 	// Instantiate http client and bucket location cache.
 	clnt.httpClient = &http.Client{
 		Jar:       jar,
-		Transport: DefaultTransport(secure),
+		Transport: transport,
 	}
 
 	// Add locked pseudo-random number generator.
 	clnt.random = rand.New(&lockedRandSource{src: rand.NewSource(time.Now().UTC().UnixNano())})
 
+	clnt.healStatusCache = &healStatusCache{}
+
 	// Return.
 	return clnt, nil
 }
@@ -185,6 +217,22 @@ func (adm *AdminClient) SetCustomTransport(customHTTPTransport http.RoundTripper
 	}
 }
 
+// SetSecure - sets the scheme of the requests sent to the server to
+// https when secure is true and to http otherwise, overriding the
+// secure flag given to New, e.g. to reach a TLS terminating sidecar
+// over http. The transport is not changed: a default transport
+// created for http uses the default TLS settings of Go when switched
+// to https, use SetCustomTransport to configure TLS.
+func (adm *AdminClient) SetSecure(secure bool) {
+	endpointURL := *adm.endpointURL
+	endpointURL.Scheme = "http"
+	if secure {
+		endpointURL.Scheme = "https"
+	}
+	adm.endpointURL = &endpointURL
+	adm.secure = secure
+}
+
 // TraceOn - enable HTTP tracing.
 func (adm *AdminClient) TraceOn(outputStream io.Writer) {
 	// if outputStream is nil then default to os.Stdout.
@@ -204,6 +252,33 @@ func (adm *AdminClient) TraceOff() {
 	adm.isTraceEnabled = false
 }
 
+// SetStrictDecoding - when enabled, heal and background heal status
+// responses containing fields unknown to this package are rejected
+// with an error naming the field, e.g. to detect server changes in
+// tests. Nested values with their own decoding, such as the heal
+// information of a disk, are not checked. Disabled by default.
+func (adm *AdminClient) SetStrictDecoding(strict bool) {
+	adm.strictDecoding = strict
+}
+
+// SetClock - sets the clock used by the time based helpers of the
+// client, e.g. to test them deterministically or to correct a skew
+// with the clock of the server. A nil clock restores time.Now. The
+// clock also drives the expiry of the cached background heal status,
+// a frozen clock keeps serving the cached status until it moves.
+func (adm *AdminClient) SetClock(now func() time.Time) {
+	adm.clock = now
+}
+
+// Now - returns the current time according to the clock of the
+// client, see SetClock.
+func (adm *AdminClient) Now() time.Time {
+	if adm.clock != nil {
+		return adm.clock()
+	}
+	return time.Now()
+}
+
 // requestMetadata - is container for all the values to make a
 // request.
 type requestData struct {
@@ -423,6 +498,26 @@ func (adm AdminClient) executeMethod(ctx context.Context, method string, reqData
 	return res, err
 }
 
+// Do - executes a signed request to an admin API endpoint which is not
+// wrapped by this client yet. relPath is relative to the admin API
+// base path, e.g. "/v3/info". Requests are retried like any other
+// admin API call.
+//
+// The caller owns the returned response: it must check the status
+// code and close the response body.
+func (adm *AdminClient) Do(ctx context.Context, method, relPath string, query url.Values, body []byte) (*http.Response, error) {
+	resp, err := adm.executeMethod(ctx, method, requestData{
+		relPath:     relPath,
+		queryValues: query,
+		content:     body,
+	})
+	if err != nil {
+		closeResponse(resp)
+		return nil, err
+	}
+	return resp, nil
+}
+
 // set User agent.
 func (adm AdminClient) setUserAgent(req *http.Request) {
 	req.Header.Set("User-Agent", libraryUserAgent)
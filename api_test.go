@@ -18,9 +18,21 @@
 package madmin_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/minio/madmin-go"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 func TestMinioAdminClient(t *testing.T) {
@@ -29,3 +41,135 @@ func TestMinioAdminClient(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestMinioAdminClientSetSecure(t *testing.T) {
+	adm, err := madmin.New("localhost:9000", "food", "food123", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schemes []string
+	adm.SetCustomTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		schemes = append(schemes, r.URL.Scheme)
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    r,
+		}, nil
+	}))
+
+	for _, secure := range []bool{false, true} {
+		adm.SetSecure(secure)
+		resp, err := adm.Do(context.Background(), http.MethodGet, "/v3/info", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		for range adm.ServiceTrace(context.Background(), madmin.ServiceTraceOpts{S3: true}) {
+		}
+	}
+	expected := []string{"http", "http", "https", "https"}
+	if strings.Join(schemes, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected schemes %v, got %v", expected, schemes)
+	}
+}
+
+func TestMinioAdminClientDo(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected PUT, got %s", r.Method)
+		}
+		if r.URL.Path != "/minio/admin/v3/new-api" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("param") != "1" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=food/") {
+			t.Errorf("Request is not signed: %q", auth)
+		}
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			t.Errorf("Unexpected content sha256 %s", r.Header.Get("X-Amz-Content-Sha256"))
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != string(body) {
+			t.Errorf("Unexpected body %s", b)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	adm, err := madmin.New(strings.TrimPrefix(srv.URL, "http://"), "food", "food123", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := adm.Do(context.Background(), http.MethodPut, "/v3/new-api", url.Values{"param": []string{"1"}}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestMinioAdminClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "madmin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "admin.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("Request is not signed")
+		}
+		switch r.URL.Path {
+		case "/minio/admin/v3/trace":
+			w.Write([]byte(`{"funcname":"s3.GetObject"}`))
+		default:
+			w.Write([]byte(`{"sets":[{"id":"pool-0-set-0"}]}`))
+		}
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	adm, err := madmin.NewWithOptions("unix://"+socketPath, &madmin.Options{
+		Creds: credentials.NewStaticV4("food", "food123", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := adm.BackgroundHealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Sets) != 1 {
+		t.Errorf("Expected 1 set, got %d", len(state.Sets))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info := <-adm.ServiceTrace(ctx, madmin.ServiceTraceOpts{S3: true})
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+	if info.Trace.FuncName != "s3.GetObject" {
+		t.Errorf("Expected s3.GetObject, got %s", info.Trace.FuncName)
+	}
+}
@@ -17,13 +17,18 @@
 package madmin
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -41,6 +46,47 @@ const (
 	HealDeepScan
 )
 
+// String returns the canonical name of the scan mode: "unknown",
+// "normal" or "deep".
+func (m HealScanMode) String() string {
+	switch m {
+	case HealNormalScan:
+		return "normal"
+	case HealDeepScan:
+		return "deep"
+	}
+	return "unknown"
+}
+
+// UnmarshalJSON - decodes the scan mode, the server encodes it as a
+// number but its canonical name is accepted as well. Scan modes are
+// always encoded as numbers. null leaves the scan mode unchanged.
+func (m *HealScanMode) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var n int
+		if err = json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*m = HealScanMode(n)
+		return nil
+	}
+	switch name {
+	case "unknown":
+		*m = HealUnknownScan
+	case "normal":
+		*m = HealNormalScan
+	case "deep":
+		*m = HealDeepScan
+	default:
+		return fmt.Errorf("unknown heal scan mode %q", name)
+	}
+	return nil
+}
+
 // HealOpts - collection of options for a heal sequence
 type HealOpts struct {
 	Recursive bool         `json:"recursive"`
@@ -49,44 +95,415 @@ type HealOpts struct {
 	Recreate  bool         `json:"recreate"` // only used when bucket needs to be healed
 	ScanMode  HealScanMode `json:"scanMode"`
 	NoLock    bool         `json:"nolock"`
+
+	// Throttle limits the resources used by the heal sequence,
+	// it requires server support and is ignored otherwise.
+	Throttle *HealThrottle `json:"throttle,omitempty"`
+
+	// ModifiedAfter and ModifiedBefore restrict the heal to objects
+	// modified in the given time window. They require server support
+	// and are ignored by older servers.
+	ModifiedAfter  *time.Time `json:"modifiedAfter,omitempty"`
+	ModifiedBefore *time.Time `json:"modifiedBefore,omitempty"`
+
+	// VersionID restricts the heal of an object to this version.
+	VersionID string `json:"versionId,omitempty"`
+
+	// Owner is advisory metadata tagging the heal sequence with the
+	// name of its creator, it is echoed back by the server in the
+	// sequence settings and does not change how the heal is run.
+	Owner string `json:"owner,omitempty"`
+
+	// ExpectDiskCount and ExpectSetCount are the number of drives
+	// per erasure set and the number of erasure sets the caller
+	// expects, the server rejects the heal if its topology differs.
+	ExpectDiskCount *int `json:"expectDiskCount,omitempty"`
+	ExpectSetCount  *int `json:"expectSetCount,omitempty"`
+
+	// Endpoint restricts the heal to the objects with shards on this
+	// drive, e.g. http://server1:9000/data1 or /data1 for a single
+	// node deployment. It requires server support, older servers
+	// ignore it and heal all the objects of the target.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// MetadataOnly restricts the heal to object and bucket metadata,
+	// data shards are not healed. It can't be combined with
+	// HealDeepScan, which verifies the data shards.
+	MetadataOnly bool `json:"metadataOnly,omitempty"`
+
+	// IncludeDeleteMarkers heals the metadata of the delete markers
+	// of versioned objects as well.
+	IncludeDeleteMarkers bool `json:"includeDeleteMarkers,omitempty"`
+
+	// ReadQuorum is a diagnostic read quorum, the server reports the
+	// recoverability of the objects as if it was their read quorum.
+	// It is advisory, only allowed with DryRun and never changes the
+	// quorum used to read or heal objects.
+	ReadQuorum *int `json:"readQuorum,omitempty"`
+
+	// MinParityLoss only reports the objects of a dry-run whose spare
+	// parity after the heal, see HealResultItem.SpareParity, is at or
+	// below MinParityLoss, e.g. 0 for the objects one drive away from
+	// data loss. Servers which ignore it report all the objects, their
+	// items are filtered by HealStatus, hence by HealWait and
+	// HealStream, see FilterByParityLoss.
+	MinParityLoss *int `json:"minParityLoss,omitempty"`
+
+	// Priority is a scheduling hint for the heal, one of
+	// HealPriorityLow, HealPriorityNormal or HealPriorityHigh. It is
+	// advisory, servers which don't support it schedule all heals
+	// alike.
+	Priority string `json:"priority,omitempty"`
+
+	// LatestVersionOnly only heals the latest version of versioned
+	// objects and skips their non-current versions. It is only
+	// meaningful for recursive heals and heals of an object, and
+	// conflicts with VersionID. Older servers ignore it and heal all
+	// the versions.
+	LatestVersionOnly bool `json:"latestVersionOnly,omitempty"`
+
+	// IdempotencyKey is sent in the Idempotency-Key header when
+	// starting the heal, servers which support it respond to starts
+	// repeated with the same key with the heal sequence started
+	// first instead of starting a new one. Older servers ignore it.
+	// It is not part of the heal settings.
+	IdempotencyKey string `json:"-"`
+}
+
+// Heal priorities, see HealOpts.Priority.
+const (
+	HealPriorityLow    = "low"
+	HealPriorityNormal = "normal"
+	HealPriorityHigh   = "high"
+)
+
+// HealThrottle - limits applied to a heal sequence, zero values
+// mean no limit.
+type HealThrottle struct {
+	MaxBytesPerSec int64 `json:"maxBytesPerSec,omitempty"`
+	MaxConcurrent  int   `json:"maxConcurrent,omitempty"`
+}
+
+// ApplyDefaults sets the default value of the options which were not
+// set, e.g. when decoding options stored before they were introduced:
+// an unknown ScanMode defaults to HealNormalScan. Other fields are
+// left as they are.
+func (o *HealOpts) ApplyDefaults() {
+	if o.ScanMode == HealUnknownScan {
+		o.ScanMode = HealNormalScan
+	}
 }
 
-// Equal returns true if no is same as o.
+// Validate returns an error if the heal options are invalid.
+func (o HealOpts) Validate() error {
+	if o.Throttle != nil {
+		if o.Throttle.MaxBytesPerSec < 0 {
+			return ErrInvalidArgument("heal throttle max bytes per second cannot be negative")
+		}
+		if o.Throttle.MaxConcurrent < 0 {
+			return ErrInvalidArgument("heal throttle max concurrent cannot be negative")
+		}
+	}
+	if o.ModifiedAfter != nil && o.ModifiedBefore != nil && o.ModifiedAfter.After(*o.ModifiedBefore) {
+		return ErrInvalidArgument("heal modified after time cannot be later than modified before time")
+	}
+	if o.ExpectDiskCount != nil && *o.ExpectDiskCount <= 0 {
+		return ErrInvalidArgument("heal expected disk count must be positive")
+	}
+	if o.ExpectSetCount != nil && *o.ExpectSetCount <= 0 {
+		return ErrInvalidArgument("heal expected set count must be positive")
+	}
+	if o.ReadQuorum != nil {
+		if !o.DryRun {
+			return ErrInvalidArgument("heal read quorum can only be used with a dry-run")
+		}
+		if *o.ReadQuorum < 1 {
+			return ErrInvalidArgument("heal read quorum must be positive")
+		}
+		if o.ExpectDiskCount != nil && *o.ReadQuorum > *o.ExpectDiskCount {
+			return ErrInvalidArgument("heal read quorum cannot exceed the expected disk count")
+		}
+	}
+	if o.MinParityLoss != nil {
+		if !o.DryRun {
+			return ErrInvalidArgument("heal min parity loss can only be used with a dry-run")
+		}
+		if *o.MinParityLoss < 0 {
+			return ErrInvalidArgument("heal min parity loss cannot be negative")
+		}
+	}
+	if o.LatestVersionOnly && o.VersionID != "" {
+		return ErrInvalidArgument("heal of the latest version only cannot target a version")
+	}
+	switch o.Priority {
+	case "", HealPriorityLow, HealPriorityNormal, HealPriorityHigh:
+	default:
+		return ErrInvalidArgument("unknown heal priority: " + o.Priority)
+	}
+	if o.MetadataOnly && o.ScanMode == HealDeepScan {
+		return ErrInvalidArgument("heal of metadata only cannot use deep scan")
+	}
+	if o.Endpoint != "" && !validDriveEndpoint(o.Endpoint) {
+		return ErrInvalidArgument("heal endpoint must be a drive URL or an absolute path: " + o.Endpoint)
+	}
+	return nil
+}
+
+// setQueryValues sets the heal options sent as query parameters.
+func (o HealOpts) setQueryValues(v url.Values) {
+	if o.Throttle != nil {
+		if o.Throttle.MaxBytesPerSec > 0 {
+			v.Set("maxBytesPerSec", strconv.FormatInt(o.Throttle.MaxBytesPerSec, 10))
+		}
+		if o.Throttle.MaxConcurrent > 0 {
+			v.Set("maxConcurrent", strconv.Itoa(o.Throttle.MaxConcurrent))
+		}
+	}
+	if o.ModifiedAfter != nil {
+		v.Set("modifiedAfter", o.ModifiedAfter.UTC().Format(time.RFC3339))
+	}
+	if o.ModifiedBefore != nil {
+		v.Set("modifiedBefore", o.ModifiedBefore.UTC().Format(time.RFC3339))
+	}
+	if o.VersionID != "" {
+		v.Set("versionId", o.VersionID)
+	}
+	if o.Owner != "" {
+		v.Set("owner", o.Owner)
+	}
+	if o.ExpectDiskCount != nil {
+		v.Set("expectDiskCount", strconv.Itoa(*o.ExpectDiskCount))
+	}
+	if o.ExpectSetCount != nil {
+		v.Set("expectSetCount", strconv.Itoa(*o.ExpectSetCount))
+	}
+	if o.Endpoint != "" {
+		v.Set("endpoint", o.Endpoint)
+	}
+	if o.MetadataOnly {
+		v.Set("metadataOnly", "true")
+	}
+	if o.IncludeDeleteMarkers {
+		v.Set("includeDeleteMarkers", "true")
+	}
+	if o.ReadQuorum != nil {
+		v.Set("readQuorum", strconv.Itoa(*o.ReadQuorum))
+	}
+	if o.MinParityLoss != nil {
+		v.Set("minParityLoss", strconv.Itoa(*o.MinParityLoss))
+	}
+	if o.Priority != "" {
+		v.Set("priority", o.Priority)
+	}
+	if o.LatestVersionOnly {
+		v.Set("latestVersionOnly", "true")
+	}
+}
+
+// setHeaders sets the heal options sent as headers.
+func (o HealOpts) setHeaders(h http.Header) {
+	if o.IdempotencyKey != "" {
+		h.Set("Idempotency-Key", o.IdempotencyKey)
+	}
+}
+
+// validDriveEndpoint returns true if endpoint is an http(s) URL with a
+// host and a drive path, or an absolute path.
+func validDriveEndpoint(endpoint string) bool {
+	if strings.HasPrefix(endpoint, "/") {
+		return true
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path != "" && u.Path != "/" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// timePtrEqual returns true if both times are nil or equal.
+func timePtrEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// intPtrEqual returns true if both integers are nil or equal.
+func intPtrEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Equal returns true if no is same as o. Recreate and NoLock only
+// affect how a heal is run and Owner is advisory, they are not compared.
 func (o HealOpts) Equal(no HealOpts) bool {
+	for _, field := range o.differences(no) {
+		switch field {
+		case "Recreate", "NoLock", "Owner":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// differences returns the names of the fields of o which differ from no.
+func (o HealOpts) differences(no HealOpts) (fields []string) {
 	if o.Recursive != no.Recursive {
-		return false
+		fields = append(fields, "Recursive")
 	}
 	if o.DryRun != no.DryRun {
-		return false
+		fields = append(fields, "DryRun")
 	}
 	if o.Remove != no.Remove {
-		return false
+		fields = append(fields, "Remove")
+	}
+	if o.Recreate != no.Recreate {
+		fields = append(fields, "Recreate")
+	}
+	if o.ScanMode != no.ScanMode {
+		fields = append(fields, "ScanMode")
+	}
+	if o.NoLock != no.NoLock {
+		fields = append(fields, "NoLock")
+	}
+	if (o.Throttle == nil) != (no.Throttle == nil) || (o.Throttle != nil && *o.Throttle != *no.Throttle) {
+		fields = append(fields, "Throttle")
+	}
+	if !timePtrEqual(o.ModifiedAfter, no.ModifiedAfter) {
+		fields = append(fields, "ModifiedAfter")
+	}
+	if !timePtrEqual(o.ModifiedBefore, no.ModifiedBefore) {
+		fields = append(fields, "ModifiedBefore")
 	}
-	return o.ScanMode == no.ScanMode
+	if o.VersionID != no.VersionID {
+		fields = append(fields, "VersionID")
+	}
+	if o.Owner != no.Owner {
+		fields = append(fields, "Owner")
+	}
+	if !intPtrEqual(o.ExpectDiskCount, no.ExpectDiskCount) {
+		fields = append(fields, "ExpectDiskCount")
+	}
+	if !intPtrEqual(o.ExpectSetCount, no.ExpectSetCount) {
+		fields = append(fields, "ExpectSetCount")
+	}
+	if o.Endpoint != no.Endpoint {
+		fields = append(fields, "Endpoint")
+	}
+	if o.MetadataOnly != no.MetadataOnly {
+		fields = append(fields, "MetadataOnly")
+	}
+	if o.IncludeDeleteMarkers != no.IncludeDeleteMarkers {
+		fields = append(fields, "IncludeDeleteMarkers")
+	}
+	if !intPtrEqual(o.ReadQuorum, no.ReadQuorum) {
+		fields = append(fields, "ReadQuorum")
+	}
+	if !intPtrEqual(o.MinParityLoss, no.MinParityLoss) {
+		fields = append(fields, "MinParityLoss")
+	}
+	if o.Priority != no.Priority {
+		fields = append(fields, "Priority")
+	}
+	if o.LatestVersionOnly != no.LatestVersionOnly {
+		fields = append(fields, "LatestVersionOnly")
+	}
+	return fields
 }
 
 // HealStartSuccess - holds information about a successfully started
-// heal operation
+// heal operation. ServerNode is the server node which accepted the
+// heal, behind a load balancer its status is best requested from the
+// same node. It is empty with older servers.
 type HealStartSuccess struct {
 	ClientToken   string    `json:"clientToken"`
 	ClientAddress string    `json:"clientAddress"`
 	StartTime     time.Time `json:"startTime"`
+	ServerNode    string    `json:"serverNode,omitempty"`
 }
 
 // HealStopSuccess - holds information about a successfully stopped
 // heal operation.
 type HealStopSuccess HealStartSuccess
 
-// HealTaskStatus - status struct for a heal task
+// HealTaskStatus - status struct for a heal task. Progress is the
+// percentage of the heal sequence scanned so far, it is only reported
+// by newer servers.
 type HealTaskStatus struct {
 	Summary       string    `json:"summary"`
 	FailureDetail string    `json:"detail"`
 	StartTime     time.Time `json:"startTime"`
 	HealSettings  HealOpts  `json:"settings"`
+	Progress      float64   `json:"progress,omitempty"`
 
 	Items []HealResultItem `json:"items,omitempty"`
 }
 
+// HealSummaryState - state of a heal sequence as reported by the
+// server in its summary.
+type HealSummaryState string
+
+// HealSummaryState constants
+const (
+	HealNotStartedState HealSummaryState = "not started"
+	HealRunningState    HealSummaryState = "running"
+	HealStoppedState    HealSummaryState = "stopped"
+	HealFinishedState   HealSummaryState = "finished"
+)
+
+// String returns the state as reported by the server, e.g. "running",
+// it is encoded as such in JSON.
+func (s HealSummaryState) String() string {
+	return string(s)
+}
+
+// HealSequenceInfo - holds information about a heal sequence known
+// to the server.
+type HealSequenceInfo struct {
+	ClientToken   string           `json:"clientToken"`
+	ClientAddress string           `json:"clientAddress"`
+	StartTime     time.Time        `json:"startTime"`
+	Bucket        string           `json:"bucket"`
+	Prefix        string           `json:"prefix"`
+	Summary       HealSummaryState `json:"summary"`
+	HealSettings  HealOpts         `json:"settings"`
+}
+
+// Active returns true if the heal sequence has not ended yet.
+func (h HealSequenceInfo) Active() bool {
+	return h.Summary == HealNotStartedState || h.Summary == HealRunningState
+}
+
+// State returns the state of the heal task as reported by the
+// server summary.
+func (s HealTaskStatus) State() HealSummaryState {
+	return HealSummaryState(s.Summary)
+}
+
+// Percent returns the scan progress of the heal sequence reported by
+// the server, between 0 and 100. It is 0 with older servers.
+func (s HealTaskStatus) Percent() float64 {
+	switch {
+	case s.Progress < 0:
+		return 0
+	case s.Progress > 100:
+		return 100
+	}
+	return s.Progress
+}
+
+// SettingsMatch returns true if the settings the heal task is running
+// with match the requested ones, along with the names of the fields
+// which differ, e.g. when the server downgraded a requested deep scan.
+func (s HealTaskStatus) SettingsMatch(requested HealOpts) (bool, []string) {
+	fields := s.HealSettings.differences(requested)
+	return len(fields) == 0, fields
+}
+
 // HealItemType - specify the type of heal operation in a healing
 // result
 type HealItemType string
@@ -99,6 +516,12 @@ const (
 	HealItemObject                      = "object"
 )
 
+// String returns the item type as reported by the server, e.g.
+// "bucket-metadata", it is encoded as such in JSON.
+func (t HealItemType) String() string {
+	return string(t)
+}
+
 // Drive state constants
 const (
 	DriveStateOk          string = "ok"
@@ -111,11 +534,25 @@ const (
 	DriveStateUnformatted        = "unformatted" // only returned by disk
 )
 
+// DriveState - state of a drive as reported in heal results and
+// disk information, one of the DriveState constants.
+type DriveState string
+
+// String returns the drive state as reported by the server, e.g.
+// "permission-denied", it is encoded as such in JSON.
+func (s DriveState) String() string {
+	return string(s)
+}
+
 // HealDriveInfo - struct for an individual drive info item.
 type HealDriveInfo struct {
 	UUID     string `json:"uuid"`
 	Endpoint string `json:"endpoint"`
 	State    string `json:"state"`
+
+	// Error is the last error seen on the drive, it is only reported
+	// by servers which support it.
+	Error string `json:"error,omitempty"`
 }
 
 // HealResultItem - struct for an individual heal result item
@@ -138,6 +575,14 @@ type HealResultItem struct {
 		Drives []HealDriveInfo `json:"drives"`
 	} `json:"after"`
 	ObjectSize int64 `json:"objectSize"`
+
+	// IsDeleteMarker is true if the item is a delete marker, the
+	// version of the delete marker is VersionID.
+	IsDeleteMarker bool `json:"isDeleteMarker,omitempty"`
+
+	// StorageClass is the storage class of the object, it is only
+	// reported by newer servers.
+	StorageClass string `json:"storageClass,omitempty"`
 }
 
 // GetMissingCounts - returns the number of missing disks before
@@ -216,6 +661,27 @@ func (hri *HealResultItem) GetOnlineCounts() (b, a int) {
 	return
 }
 
+// startRequest validates the heal options of a heal of prefix and
+// returns the body, query values and headers of the request starting
+// it.
+func (o HealOpts) startRequest(prefix string) (body []byte, queryVals url.Values, headers http.Header, err error) {
+	o.ApplyDefaults()
+	if err = o.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if o.LatestVersionOnly && !o.Recursive && prefix == "" {
+		return nil, nil, nil, ErrInvalidArgument("heal of the latest version only requires a recursive or object heal")
+	}
+	if body, err = json.Marshal(o); err != nil {
+		return nil, nil, nil, err
+	}
+	queryVals = make(url.Values)
+	o.setQueryValues(queryVals)
+	headers = make(http.Header)
+	o.setHeaders(headers)
+	return body, queryVals, headers, nil
+}
+
 // Heal - API endpoint to start heal and to fetch status
 // forceStart and forceStop are mutually exclusive, you can either
 // set one of them to 'true'. If both are set 'forceStart' will be
@@ -228,22 +694,25 @@ func (adm *AdminClient) Heal(ctx context.Context, bucket, prefix string,
 		return healStart, healTaskStatus, ErrInvalidArgument("forceStart and forceStop set to true is not allowed")
 	}
 
-	body, err := json.Marshal(healOpts)
+	body, queryVals, headers, err := healOpts.startRequest(prefix)
 	if err != nil {
 		return healStart, healTaskStatus, err
 	}
 
-	path := fmt.Sprintf(adminAPIPrefix+"/heal/%s", bucket)
-	if bucket != "" && prefix != "" {
-		path += "/" + prefix
-	}
+	path := healRelPath(bucket, prefix)
 
 	// execute POST request to heal api
-	queryVals := make(url.Values)
 	if clientToken != "" {
+		queryVals = make(url.Values)
 		queryVals.Set("clientToken", clientToken)
 		body = []byte{}
 	}
+	if clientToken != "" || forceStart || forceStop {
+		// Only plain starts carry the heal headers, a forced start
+		// or stop must not be deduplicated with the start by its
+		// idempotency key.
+		headers = make(http.Header)
+	}
 
 	// Anyone can be set, either force start or forceStop.
 	if forceStart {
@@ -254,9 +723,10 @@ func (adm *AdminClient) Heal(ctx context.Context, bucket, prefix string,
 
 	resp, err := adm.executeMethod(ctx,
 		http.MethodPost, requestData{
-			relPath:     path,
-			content:     body,
-			queryValues: queryVals,
+			relPath:       path,
+			content:       body,
+			queryValues:   queryVals,
+			customHeaders: headers,
 		})
 	defer closeResponse(resp)
 	if err != nil {
@@ -271,6 +741,9 @@ func (adm *AdminClient) Heal(ctx context.Context, bucket, prefix string,
 	if err != nil {
 		return healStart, healTaskStatus, err
 	}
+	if len(bytes.TrimSpace(respBytes)) == 0 {
+		return healStart, healTaskStatus, ErrEmptyResponse
+	}
 
 	// Was it a status request?
 	if clientToken == "" {
@@ -278,22 +751,350 @@ func (adm *AdminClient) Heal(ctx context.Context, bucket, prefix string,
 		// similar struct as healStart will have the
 		// heal sequence information about the heal which
 		// was stopped.
-		err = json.Unmarshal(respBytes, &healStart)
+		err = decodeHealResponse(respBytes, &healStart, adm.strictDecoding)
 	} else {
-		err = json.Unmarshal(respBytes, &healTaskStatus)
+		err = decodeHealResponse(respBytes, &healTaskStatus, adm.strictDecoding)
+	}
+	return healStart, healTaskStatus, err
+}
+
+// healRelPath returns the path of the heal API for bucket and prefix.
+func healRelPath(bucket, prefix string) string {
+	path := fmt.Sprintf(adminAPIPrefix+"/heal/%s", bucket)
+	if bucket != "" && prefix != "" {
+		path += "/" + prefix
+	}
+	return path
+}
+
+// decodeHealResponse decodes a successful heal response into v. The
+// server may respond with an error after the success status has been
+// sent, such a body is recognized by its "Code" and "Message" fields
+// and returned as an ErrorResponse. Bodies which can't be decoded
+// into v, or with unknown fields when strict is true, are reported
+// with ErrUnexpectedResponse.
+func decodeHealResponse(respBytes []byte, v interface{}, strict bool) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(respBytes, &fields); err == nil {
+		_, hasCode := fields["Code"]
+		_, hasMessage := fields["Message"]
+		if hasCode && hasMessage {
+			var errResp ErrorResponse
+			if err = json.Unmarshal(respBytes, &errResp); err == nil {
+				return errResp
+			}
+		}
+	}
+	if err := decodeJSON(respBytes, v, strict); err != nil {
+		if _, ok := err.(ErrorResponse); ok {
+			return err
+		}
+		return ErrUnexpectedResponse(fmt.Sprintf("Unable to parse heal response: %v", err))
+	}
+	return nil
+}
+
+// ErrHealNotStarted is returned by HealStatus when the server has no
+// heal sequence for the client token, i.e. it was never started,
+// has been purged after completion or the token is invalid.
+var ErrHealNotStarted = errors.New("no heal sequence found for the client token")
+
+// Heal error codes returned by the server when the client token does
+// not belong to any known heal sequence.
+var healNotStartedErrCodes = map[string]struct{}{
+	"XMinioHealNoSuchProcess":      {},
+	"XMinioHealInvalidClientToken": {},
+}
+
+// HealStatus - fetches the status of the heal sequence identified by
+// clientToken on bucket/prefix. A sequence which ran to completion
+// is reported with a nil error and a status in the HealFinishedState
+// (or HealStoppedState) state, whereas ErrHealNotStarted is returned
+// when the server does not know about the sequence. When the settings
+// of the sequence carry MinParityLoss, the items are filtered with
+// FilterByParityLoss in case the server ignored the option.
+func (adm *AdminClient) HealStatus(ctx context.Context, bucket, prefix, clientToken string) (HealTaskStatus, error) {
+	if clientToken == "" {
+		return HealTaskStatus{}, ErrInvalidArgument("clientToken cannot be empty")
+	}
+	_, status, err := adm.Heal(ctx, bucket, prefix, HealOpts{}, clientToken, false, false)
+	if err != nil {
+		if _, ok := healNotStartedErrCodes[ToErrorResponse(err).Code]; ok {
+			return HealTaskStatus{}, ErrHealNotStarted
+		}
+		return HealTaskStatus{}, err
+	}
+	if maxSpare := status.HealSettings.MinParityLoss; maxSpare != nil {
+		status.Items = FilterByParityLoss(status.Items, *maxSpare)
+	}
+	return status, nil
+}
+
+// ListHealSequences - lists the heal sequences known to the server,
+// including the ones which have already ended.
+func (adm *AdminClient) ListHealSequences(ctx context.Context) ([]HealSequenceInfo, error) {
+	return adm.listHealSequencesPage(ctx, "", 0)
+}
+
+// listHealSequencesPage lists at most maxKeys heal sequences after the
+// sequence with the marker client token. All the sequences are listed
+// when maxKeys is zero.
+func (adm *AdminClient) listHealSequencesPage(ctx context.Context, marker string, maxKeys int) ([]HealSequenceInfo, error) {
+	queryVals := make(url.Values)
+	if marker != "" {
+		queryVals.Set("marker", marker)
+	}
+	if maxKeys > 0 {
+		queryVals.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	resp, err := adm.executeMethod(ctx,
+		http.MethodGet,
+		requestData{
+			relPath:     adminAPIPrefix + "/heal-sequences",
+			queryValues: queryVals,
+		})
+	defer closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpRespToErrorResponse(resp)
+	}
+
+	var sequences []HealSequenceInfo
+	if err = json.NewDecoder(resp.Body).Decode(&sequences); err != nil {
+		return nil, err
+	}
+	return sequences, nil
+}
+
+// ListHealSequencesByOwner - lists the heal sequences known to the
+// server which were started with the given HealOpts.Owner.
+func (adm *AdminClient) ListHealSequencesByOwner(ctx context.Context, owner string) ([]HealSequenceInfo, error) {
+	sequences, err := adm.ListHealSequences(ctx)
+	if err != nil {
+		return nil, err
 	}
+	owned := sequences[:0]
+	for _, seq := range sequences {
+		if seq.HealSettings.Owner == owner {
+			owned = append(owned, seq)
+		}
+	}
+	return owned, nil
+}
+
+// OldestHealAge - returns the time elapsed since the start of the
+// oldest active heal sequence according to the clock of the client,
+// see SetClock, along with its client token. Zero and an
+// empty token are returned when no heal sequence is active.
+func (adm *AdminClient) OldestHealAge(ctx context.Context) (time.Duration, string, error) {
+	sequences, err := adm.ListHealSequences(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	var oldest *HealSequenceInfo
+	for i, seq := range sequences {
+		if seq.Active() && (oldest == nil || seq.StartTime.Before(oldest.StartTime)) {
+			oldest = &sequences[i]
+		}
+	}
+	if oldest == nil {
+		return 0, "", nil
+	}
+	return adm.Now().Sub(oldest.StartTime), oldest.ClientToken, nil
+}
+
+// HealStartIfAbsent - starts a heal sequence on bucket/prefix unless
+// an active sequence with the same settings is already running on
+// the same path. In that case the existing sequence is returned and
+// started is false.
+func (adm *AdminClient) HealStartIfAbsent(ctx context.Context, bucket, prefix string, healOpts HealOpts) (healStart HealStartSuccess, started bool, err error) {
+	healOpts.ApplyDefaults()
+	sequences, err := adm.ListHealSequences(ctx)
+	if err != nil {
+		return healStart, false, err
+	}
+	for _, seq := range sequences {
+		if seq.Bucket != bucket || seq.Prefix != prefix || !seq.Active() {
+			continue
+		}
+		if !seq.HealSettings.Equal(healOpts) {
+			continue
+		}
+		return HealStartSuccess{
+			ClientToken:   seq.ClientToken,
+			ClientAddress: seq.ClientAddress,
+			StartTime:     seq.StartTime,
+		}, false, nil
+	}
+
+	healStart, _, err = adm.Heal(ctx, bucket, prefix, healOpts, "", false, false)
+	if err != nil {
+		return healStart, false, err
+	}
+	return healStart, true, nil
+}
+
+// HealStopByPrefix - force stops the active heal sequence running on
+// bucket/prefix without requiring its client token. ErrHealNotStarted
+// is returned when no such sequence is running.
+func (adm *AdminClient) HealStopByPrefix(ctx context.Context, bucket, prefix string) error {
+	sequences, err := adm.ListHealSequences(ctx)
 	if err != nil {
-		// May be the server responded with error after success
-		// message, handle it separately here.
-		var errResp ErrorResponse
-		err = json.Unmarshal(respBytes, &errResp)
-		if err != nil {
-			// Unknown structure return error anyways.
-			return healStart, healTaskStatus, err
+		return err
+	}
+	for _, seq := range sequences {
+		if seq.Bucket != bucket || seq.Prefix != prefix || !seq.Active() {
+			continue
+		}
+		_, _, err = adm.Heal(ctx, bucket, prefix, seq.HealSettings, "", false, true)
+		return err
+	}
+	return ErrHealNotStarted
+}
+
+// HealMany - starts a heal sequence for each of the prefixes under
+// bucket concurrently. The heal sequences that were started are
+// returned keyed by prefix, failures are reported as a *MultiError
+// keyed by prefix.
+func (adm *AdminClient) HealMany(ctx context.Context, bucket string, prefixes []string, healOpts HealOpts) (map[string]HealStartSuccess, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		merr    MultiError
+		started = make(map[string]HealStartSuccess, len(prefixes))
+	)
+	for _, prefix := range prefixes {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			healStart, _, err := adm.Heal(ctx, bucket, prefix, healOpts, "", false, false)
+			if err != nil {
+				merr.Add(prefix, err)
+				return
+			}
+			mu.Lock()
+			started[prefix] = healStart
+			mu.Unlock()
+		}(prefix)
+	}
+	wg.Wait()
+	return started, merr.ErrorOrNil()
+}
+
+// MaxWeightedHealConcurrency is the maximum number of heal sequences
+// run concurrently by HealManyWeighted.
+var MaxWeightedHealConcurrency = 4
+
+// healManyPollInterval is the interval at which HealManyWeighted polls
+// the status of the heal sequences it started.
+var healManyPollInterval = time.Second
+
+// WeightedHealJob - a heal of bucket/prefix scheduled with the given
+// weight by HealManyWeighted.
+type WeightedHealJob struct {
+	Bucket string
+	Prefix string
+	Weight int
+}
+
+// HealManyResult - outcome of a heal job run by HealManyWeighted.
+type HealManyResult struct {
+	Bucket    string
+	Prefix    string
+	HealStart HealStartSuccess
+	// Status is the final status of the heal sequence.
+	Status HealTaskStatus
+	Err    error
+}
+
+// nextWeightedJob returns the index of the pending job to run next: the
+// job of the bucket with the fewest running jobs relative to its
+// weight, favoring higher weights and then the order of the jobs.
+func nextWeightedJob(pending []WeightedHealJob, running map[string]int) int {
+	next := 0
+	for i, job := range pending[1:] {
+		best := pending[next]
+		// Compare running/weight ratios without dividing.
+		lhs, rhs := running[job.Bucket]*best.Weight, running[best.Bucket]*job.Weight
+		if lhs < rhs || (lhs == rhs && job.Weight > best.Weight) {
+			next = i + 1
 		}
-		return healStart, healTaskStatus, errResp
 	}
-	return healStart, healTaskStatus, nil
+	return next
+}
+
+// HealManyWeighted - runs a heal sequence for each of the jobs, at most
+// MaxWeightedHealConcurrency at a time, and sends the outcome of each
+// job on the returned channel, which is closed once all the jobs are
+// done. Concurrency slots are shared among buckets in proportion to
+// the weight of their jobs, e.g. a bucket of weight 3 runs three heals
+// for each heal of a bucket of weight 1. A slot is held until the heal
+// sequence ends. Jobs not started when ctx is canceled are reported
+// with the context error. The heal sequences still running are force
+// stopped with a fresh context bounded to a few seconds, as ctx can't
+// carry the stop request anymore, and reported with the context error.
+// The returned channel must be drained.
+func (adm *AdminClient) HealManyWeighted(ctx context.Context, jobs []WeightedHealJob, opts HealOpts) (<-chan HealManyResult, error) {
+	for _, job := range jobs {
+		if job.Weight <= 0 {
+			return nil, ErrInvalidArgument("heal job weight must be positive")
+		}
+	}
+	opts.ApplyDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	slots := MaxWeightedHealConcurrency
+	if slots < 1 {
+		slots = 1
+	}
+
+	resultCh := make(chan HealManyResult)
+	go func() {
+		defer close(resultCh)
+
+		pending := append([]WeightedHealJob{}, jobs...)
+		running := make(map[string]int)
+		doneCh := make(chan HealManyResult)
+		inflight := 0
+		for len(pending) > 0 || inflight > 0 {
+			for inflight < slots && len(pending) > 0 && ctx.Err() == nil {
+				i := nextWeightedJob(pending, running)
+				job := pending[i]
+				pending = append(pending[:i], pending[i+1:]...)
+				running[job.Bucket]++
+				inflight++
+				go func() {
+					doneCh <- adm.runWeightedHealJob(ctx, job, opts)
+				}()
+			}
+			if inflight == 0 {
+				// ctx was canceled, report the jobs never started.
+				for _, job := range pending {
+					resultCh <- HealManyResult{Bucket: job.Bucket, Prefix: job.Prefix, Err: ctx.Err()}
+				}
+				return
+			}
+			result := <-doneCh
+			running[result.Bucket]--
+			inflight--
+			resultCh <- result
+		}
+	}()
+	return resultCh, nil
+}
+
+func (adm *AdminClient) runWeightedHealJob(ctx context.Context, job WeightedHealJob, opts HealOpts) HealManyResult {
+	result := HealManyResult{Bucket: job.Bucket, Prefix: job.Prefix}
+	result.HealStart, _, result.Err = adm.Heal(ctx, job.Bucket, job.Prefix, opts, "", false, false)
+	if result.Err != nil {
+		return result
+	}
+	result.Status, result.Err = adm.HealWait(ctx, job.Bucket, job.Prefix, result.HealStart.ClientToken, healManyPollInterval)
+	return result
 }
 
 // MRFStatus exposes MRF metrics of a server
@@ -307,6 +1108,27 @@ type MRFStatus struct {
 	Started time.Time `json:"started"`
 }
 
+// UnmarshalJSON - decodes the MRF status, counters may be encoded
+// either as numbers or as strings.
+func (m *MRFStatus) UnmarshalJSON(data []byte) error {
+	type mrfStatus MRFStatus
+	aux := struct {
+		*mrfStatus
+		BytesHealed jsonUint64 `json:"bytes_healed"`
+		ItemsHealed jsonUint64 `json:"items_healed"`
+		TotalItems  jsonUint64 `json:"total_items"`
+		TotalBytes  jsonUint64 `json:"total_bytes"`
+	}{mrfStatus: (*mrfStatus)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	m.BytesHealed = uint64(aux.BytesHealed)
+	m.ItemsHealed = uint64(aux.ItemsHealed)
+	m.TotalItems = uint64(aux.TotalItems)
+	m.TotalBytes = uint64(aux.TotalBytes)
+	return nil
+}
+
 // BgHealState represents the status of the background heal
 type BgHealState struct {
 	// List of offline endpoints with no background heal state info
@@ -321,6 +1143,11 @@ type BgHealState struct {
 	MRF map[string]MRFStatus `json:"mrf"`
 	// Parity per storage class
 	SCParity map[string]int `json:"sc_parity"`
+	// Paused is true if background healing is paused
+	Paused bool `json:"paused,omitempty"`
+	// Indices of the pools being decommissioned, not reported by
+	// older servers
+	Decommissioning []int `json:"decommissioning,omitempty"`
 }
 
 // SetStatus contains information about the heal status of a set.
@@ -334,6 +1161,26 @@ type SetStatus struct {
 	Disks        []Disk `json:"disks"`
 }
 
+// Backlog - returns the number of objects still to be healed in the
+// set, i.e. the objects not yet healed by its least advanced healing
+// disk, as the disks of a set heal the same objects concurrently.
+// Disks without heal information are not healing or their progress is
+// unknown and are excluded, false is returned when no disk of the set
+// reports heal information, the backlog is then unknown.
+func (s SetStatus) Backlog() (int, bool) {
+	backlog, known := 0, false
+	for _, disk := range s.Disks {
+		if disk.HealInfo == nil {
+			continue
+		}
+		known = true
+		if left := s.TotalObjects - int(disk.HealInfo.ItemsHealed); left > backlog {
+			backlog = left
+		}
+	}
+	return backlog, known
+}
+
 // HealingDisk contains information about
 type HealingDisk struct {
 	// Copied from cmd/background-newdisks-heal-ops.go
@@ -371,6 +1218,35 @@ type HealingDisk struct {
 	// future add more tracking capabilities
 }
 
+// UnmarshalJSON - decodes the healing disk information, counters may
+// be encoded either as numbers or as strings.
+func (h *HealingDisk) UnmarshalJSON(data []byte) error {
+	type healingDisk HealingDisk
+	aux := struct {
+		*healingDisk
+		ObjectsTotalCount jsonUint64 `json:"objects_total_count"`
+		ObjectsTotalSize  jsonUint64 `json:"objects_total_size"`
+		ItemsHealed       jsonUint64 `json:"items_healed"`
+		ItemsFailed       jsonUint64 `json:"items_failed"`
+		BytesDone         jsonUint64 `json:"bytes_done"`
+		BytesFailed       jsonUint64 `json:"bytes_failed"`
+		ObjectsHealed     jsonUint64 `json:"objects_healed"`
+		ObjectsFailed     jsonUint64 `json:"objects_failed"`
+	}{healingDisk: (*healingDisk)(h)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	h.ObjectsTotalCount = uint64(aux.ObjectsTotalCount)
+	h.ObjectsTotalSize = uint64(aux.ObjectsTotalSize)
+	h.ItemsHealed = uint64(aux.ItemsHealed)
+	h.ItemsFailed = uint64(aux.ItemsFailed)
+	h.BytesDone = uint64(aux.BytesDone)
+	h.BytesFailed = uint64(aux.BytesFailed)
+	h.ObjectsHealed = uint64(aux.ObjectsHealed)
+	h.ObjectsFailed = uint64(aux.ObjectsFailed)
+	return nil
+}
+
 // Merge others into b.
 func (b *BgHealState) Merge(others ...BgHealState) {
 	// SCParity is the same from all nodes, just pick
@@ -385,12 +1261,18 @@ func (b *BgHealState) Merge(others ...BgHealState) {
 		b.MRF = make(map[string]MRFStatus)
 	}
 	for _, other := range others {
+		b.Paused = b.Paused || other.Paused
 		for _, offlineEndpoint := range other.OfflineEndpoints {
 			b.OfflineEndpoints = append(b.OfflineEndpoints, offlineEndpoint)
 		}
 		for k, v := range other.MRF {
 			b.MRF[k] = v
 		}
+		for _, pool := range other.Decommissioning {
+			if !b.isDecommissioning(pool) {
+				b.Decommissioning = append(b.Decommissioning, pool)
+			}
+		}
 		b.ScannedItemsCount += other.ScannedItemsCount
 		if len(b.Sets) == 0 {
 			b.Sets = make([]SetStatus, len(other.Sets))
@@ -433,13 +1315,162 @@ func (b *BgHealState) Merge(others ...BgHealState) {
 	})
 }
 
+// MergeChan merges the states received from in into b, one at a
+// time, until in is closed or ctx is canceled.
+func (b *BgHealState) MergeChan(ctx context.Context, in <-chan BgHealState) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case other, ok := <-in:
+			if !ok {
+				return
+			}
+			b.Merge(other)
+		}
+	}
+}
+
+// MergeConflict - a value reported differently by two nodes, see
+// BgHealState.MergeWithConflicts.
+type MergeConflict struct {
+	// SetID is the ID of the set the value belongs to, it is empty
+	// for cluster wide values.
+	SetID    string `json:"setId,omitempty"`
+	Field    string `json:"field"`
+	Existing string `json:"existing"`
+	Other    string `json:"other"`
+}
+
+// conflicts returns the values of other contradicting the ones of b.
+func (b BgHealState) conflicts(other BgHealState) []MergeConflict {
+	var conflicts []MergeConflict
+	for class, parity := range other.SCParity {
+		if existing, ok := b.SCParity[class]; ok && existing != parity {
+			conflicts = append(conflicts, MergeConflict{
+				Field:    "SCParity." + class,
+				Existing: strconv.Itoa(existing),
+				Other:    strconv.Itoa(parity),
+			})
+		}
+	}
+	for _, set := range other.Sets {
+		for _, existing := range b.Sets {
+			if existing.ID != set.ID {
+				continue
+			}
+			if existing.PoolIndex != set.PoolIndex || existing.SetIndex != set.SetIndex {
+				conflicts = append(conflicts, MergeConflict{
+					SetID:    set.ID,
+					Field:    "Index",
+					Existing: fmt.Sprintf("%d/%d", existing.PoolIndex, existing.SetIndex),
+					Other:    fmt.Sprintf("%d/%d", set.PoolIndex, set.SetIndex),
+				})
+			}
+			if existing.TotalObjects != set.TotalObjects {
+				conflicts = append(conflicts, MergeConflict{
+					SetID:    set.ID,
+					Field:    "TotalObjects",
+					Existing: strconv.Itoa(existing.TotalObjects),
+					Other:    strconv.Itoa(set.TotalObjects),
+				})
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].SetID != conflicts[j].SetID {
+			return conflicts[i].SetID < conflicts[j].SetID
+		}
+		return conflicts[i].Field < conflicts[j].Field
+	})
+	return conflicts
+}
+
+// MergeWithConflicts - merges others into b like Merge and returns the
+// values on which the nodes disagree: the parity of a storage class,
+// and the indices and total objects of a set. Conflicts may reveal a
+// split-brain, the values kept in b are the ones Merge keeps.
+func (b *BgHealState) MergeWithConflicts(others ...BgHealState) []MergeConflict {
+	var conflicts []MergeConflict
+	for _, other := range others {
+		conflicts = append(conflicts, b.conflicts(other)...)
+		b.Merge(other)
+	}
+	return conflicts
+}
+
+// Backlogs - returns the heal backlog of each set keyed by set ID.
+// Sets whose backlog is unknown, see SetStatus.Backlog, are omitted.
+func (b BgHealState) Backlogs() map[string]int {
+	backlogs := make(map[string]int, len(b.Sets))
+	for _, set := range b.Sets {
+		if backlog, ok := set.Backlog(); ok {
+			backlogs[set.ID] = backlog
+		}
+	}
+	return backlogs
+}
+
 // BackgroundHealStatus returns the background heal status of the
-// current server or cluster.
+// current server or cluster. When a cache TTL is set with
+// SetStatusCacheTTL, a copy of a recent status may be returned.
 func (adm *AdminClient) BackgroundHealStatus(ctx context.Context) (BgHealState, error) {
+	if adm.healStatusCache.enabled() {
+		return adm.healStatusCache.get(ctx, adm.Now, adm.healStatusFetchTimeout(), func(ctx context.Context) (BgHealState, error) {
+			return adm.backgroundHealStatus(ctx, nil)
+		})
+	}
+	return adm.backgroundHealStatus(ctx, nil)
+}
+
+// healStatusFetchTimeout returns the timeout of the background heal
+// status requests shared through the cache: the timeout of the HTTP
+// client when set, defaultHealStatusFetchTimeout otherwise.
+func (adm *AdminClient) healStatusFetchTimeout() time.Duration {
+	if adm.httpClient != nil && adm.httpClient.Timeout > 0 {
+		return adm.httpClient.Timeout
+	}
+	return defaultHealStatusFetchTimeout
+}
+
+// BackgroundHealStatusPool returns the background heal status of the
+// sets of a single pool. Servers which don't support scoping the
+// status return the status of all the pools, the sets of other pools
+// are then filtered out by the client. The status is never cached.
+func (adm *AdminClient) BackgroundHealStatusPool(ctx context.Context, poolIndex int) (BgHealState, error) {
+	if poolIndex < 0 {
+		return BgHealState{}, ErrInvalidArgument("pool index cannot be negative")
+	}
+	queryValues := url.Values{}
+	queryValues.Set("pool", strconv.Itoa(poolIndex))
+	state, err := adm.backgroundHealStatus(ctx, queryValues)
+	if err != nil {
+		return BgHealState{}, err
+	}
+
+	sets := state.Sets[:0]
+	for _, set := range state.Sets {
+		if set.PoolIndex == poolIndex {
+			sets = append(sets, set)
+		}
+	}
+	state.Sets = sets
+	decommissioning := state.isDecommissioning(poolIndex)
+	state.Decommissioning = nil
+	if decommissioning {
+		state.Decommissioning = []int{poolIndex}
+	}
+	return state, nil
+}
+
+func (adm *AdminClient) backgroundHealStatus(ctx context.Context, queryValues url.Values) (BgHealState, error) {
 	// Execute POST request to background heal status api
 	resp, err := adm.executeMethod(ctx,
 		http.MethodPost,
-		requestData{relPath: adminAPIPrefix + "/background-heal/status"})
+		requestData{
+			relPath:     adminAPIPrefix + "/background-heal/status",
+			queryValues: queryValues,
+		})
 	if err != nil {
 		return BgHealState{}, err
 	}
@@ -453,12 +1484,205 @@ func (adm *AdminClient) BackgroundHealStatus(ctx context.Context) (BgHealState,
 	if err != nil {
 		return BgHealState{}, err
 	}
+	if len(bytes.TrimSpace(respBytes)) == 0 {
+		return BgHealState{}, ErrEmptyResponse
+	}
 
 	var healState BgHealState
 
-	err = json.Unmarshal(respBytes, &healState)
+	err = decodeJSON(respBytes, &healState, adm.strictDecoding)
 	if err != nil {
 		return BgHealState{}, err
 	}
 	return healState, nil
 }
+
+// PauseBackgroundHeal pauses the background healing of the cluster,
+// the progress made so far is kept and healing continues from there
+// once resumed with ResumeBackgroundHeal. A NotSupportedError is
+// returned by servers which can't pause background healing.
+func (adm *AdminClient) PauseBackgroundHeal(ctx context.Context) error {
+	return adm.backgroundHealAction(ctx, "pause")
+}
+
+// ResumeBackgroundHeal resumes the background healing paused with
+// PauseBackgroundHeal.
+func (adm *AdminClient) ResumeBackgroundHeal(ctx context.Context) error {
+	return adm.backgroundHealAction(ctx, "resume")
+}
+
+// StopSetHeal stops the background healing of a single erasure set,
+// the other sets keep healing. The set must be part of the background
+// heal status. A NotSupportedError is returned by servers which can't
+// stop the heal of a set.
+func (adm *AdminClient) StopSetHeal(ctx context.Context, poolIndex, setIndex int) error {
+	if poolIndex < 0 || setIndex < 0 {
+		return ErrInvalidArgument("pool and set indices cannot be negative")
+	}
+	state, err := adm.BackgroundHealStatus(ctx)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, set := range state.Sets {
+		if set.PoolIndex == poolIndex && set.SetIndex == setIndex {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrInvalidArgument(fmt.Sprintf("no set %d in pool %d", setIndex, poolIndex))
+	}
+
+	body, err := json.Marshal(struct {
+		Pool int `json:"pool"`
+		Set  int `json:"set"`
+	}{poolIndex, setIndex})
+	if err != nil {
+		return err
+	}
+	resp, err := adm.executeMethod(ctx,
+		http.MethodPost,
+		requestData{
+			relPath: adminAPIPrefix + "/background-heal/stop-set",
+			content: body,
+		})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return toNotSupportedError("background-heal/stop-set", httpRespToErrorResponse(resp))
+	}
+	return nil
+}
+
+func (adm *AdminClient) backgroundHealAction(ctx context.Context, action string) error {
+	resp, err := adm.executeMethod(ctx,
+		http.MethodPost,
+		requestData{relPath: adminAPIPrefix + "/background-heal/" + action})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return toNotSupportedError("background-heal/"+action, httpRespToErrorResponse(resp))
+	}
+	return nil
+}
+
+// StorageClassParity - returns the parity of each storage class, keyed
+// by upper cased storage class name, e.g. "STANDARD". The parity is
+// fetched from the storage class parity API, servers which don't
+// support it are asked for the much larger background heal status
+// instead. Parities which are not positive, or storage classes
+// reported more than once with different parities, are rejected.
+func (adm *AdminClient) StorageClassParity(ctx context.Context) (map[string]int, error) {
+	parity, err := adm.storageClassParity(ctx)
+	if _, ok := err.(NotSupportedError); ok {
+		var state BgHealState
+		state, err = adm.BackgroundHealStatus(ctx)
+		parity = state.SCParity
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make(map[string]int, len(parity))
+	for class, p := range parity {
+		if p <= 0 {
+			return nil, ErrUnexpectedResponse(fmt.Sprintf("Invalid parity %d for storage class %s", p, class))
+		}
+		class = strings.ToUpper(class)
+		if existing, ok := normalized[class]; ok && existing != p {
+			return nil, ErrUnexpectedResponse(fmt.Sprintf("Conflicting parities %d and %d for storage class %s", existing, p, class))
+		}
+		normalized[class] = p
+	}
+	return normalized, nil
+}
+
+func (adm *AdminClient) storageClassParity(ctx context.Context) (map[string]int, error) {
+	resp, err := adm.executeMethod(ctx,
+		http.MethodGet,
+		requestData{relPath: adminAPIPrefix + "/storage-class/parity"})
+	defer closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, toNotSupportedError("storage-class/parity", httpRespToErrorResponse(resp))
+	}
+
+	var parity map[string]int
+	if err = json.NewDecoder(resp.Body).Decode(&parity); err != nil {
+		return nil, err
+	}
+	return parity, nil
+}
+
+// HealCapabilities - heal features supported by a server, see
+// AdminClient.HealCapabilities.
+type HealCapabilities struct {
+	// PerPool is true if the background heal status can be scoped to
+	// a pool, see BackgroundHealStatusPool.
+	PerPool bool `json:"perPool"`
+	// Throttle is true if HealOpts.Throttle is honored.
+	Throttle bool `json:"throttle"`
+	// MetadataOnly is true if HealOpts.MetadataOnly is honored.
+	MetadataOnly bool `json:"metadataOnly"`
+	// DeleteMarkers is true if HealOpts.IncludeDeleteMarkers is
+	// honored.
+	DeleteMarkers bool `json:"deleteMarkers"`
+}
+
+// HealCapabilities - returns the heal features supported by the
+// server, so that callers can avoid sending options the server would
+// reject or ignore. Servers which don't report their capabilities
+// support none of them, a zero HealCapabilities is returned for them.
+func (adm *AdminClient) HealCapabilities(ctx context.Context) (HealCapabilities, error) {
+	resp, err := adm.executeMethod(ctx,
+		http.MethodGet,
+		requestData{relPath: adminAPIPrefix + "/heal-capabilities"})
+	defer closeResponse(resp)
+	if err != nil {
+		return HealCapabilities{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = toNotSupportedError("heal-capabilities", httpRespToErrorResponse(resp))
+		if _, ok := err.(NotSupportedError); ok {
+			return HealCapabilities{}, nil
+		}
+		return HealCapabilities{}, err
+	}
+
+	var caps HealCapabilities
+	if err = json.NewDecoder(resp.Body).Decode(&caps); err != nil {
+		return HealCapabilities{}, err
+	}
+	return caps, nil
+}
+
+// ValidateAgainst returns an error listing the fields of o which use
+// heal features the server does not support according to caps, see
+// AdminClient.HealCapabilities.
+func (o HealOpts) ValidateAgainst(caps HealCapabilities) error {
+	var unsupported []string
+	if o.Throttle != nil && !caps.Throttle {
+		unsupported = append(unsupported, "Throttle")
+	}
+	if o.MetadataOnly && !caps.MetadataOnly {
+		unsupported = append(unsupported, "MetadataOnly")
+	}
+	if o.IncludeDeleteMarkers && !caps.DeleteMarkers {
+		unsupported = append(unsupported, "IncludeDeleteMarkers")
+	}
+	if len(unsupported) > 0 {
+		return ErrInvalidArgument("heal options not supported by the server: " + strings.Join(unsupported, ", "))
+	}
+	return nil
+}
@@ -17,9 +17,65 @@
 package madmin
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// Tests every heal enum value survives a JSON round-trip and has a
+// string form.
+func TestHealEnumsJSON(t *testing.T) {
+	values := []fmt.Stringer{
+		HealUnknownScan, HealNormalScan, HealDeepScan,
+		HealNotStartedState, HealRunningState, HealStoppedState, HealFinishedState,
+		HealItemMetadata, HealItemType(HealItemBucket), HealItemType(HealItemBucketMetadata), HealItemType(HealItemObject),
+		DriveState(DriveStateOk), DriveState(DriveStateOffline), DriveState(DriveStateCorrupt),
+		DriveState(DriveStateMissing), DriveState(DriveStatePermission), DriveState(DriveStateFaulty),
+		DriveState(DriveStateUnknown), DriveState(DriveStateUnformatted),
+		HealFailureNone, HealFailureUnknown, HealFailureInsufficientDrives,
+		HealFailureBitrotDetected, HealFailurePermissionDenied,
+	}
+	for _, value := range values {
+		if value.String() == "" {
+			t.Errorf("%#v: Expected a string form", value)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("%#v: %v", value, err)
+		}
+		decoded := reflect.New(reflect.TypeOf(value))
+		if err = json.Unmarshal(data, decoded.Interface()); err != nil {
+			t.Fatalf("%#v: %v", value, err)
+		}
+		if got := decoded.Elem().Interface(); got != value {
+			t.Errorf("Expected %#v, got %#v", value, got)
+		}
+	}
+
+	var mode HealScanMode
+	if err := json.Unmarshal([]byte(`"deep"`), &mode); err != nil || mode != HealDeepScan {
+		t.Errorf("Expected deep scan from its name, got %v (%v)", mode, err)
+	}
+	if err := json.Unmarshal([]byte(`"bitrot"`), &mode); err == nil {
+		t.Error("Expected error for unknown scan mode name")
+	}
+
+	var status HealTaskStatus
+	if err := json.Unmarshal([]byte(`{"settings":{"scanMode":null}}`), &status); err != nil {
+		t.Errorf("Expected a null scan mode to be ignored, got %v", err)
+	}
+}
+
 // Tests heal drives missing and offline counts.
 func TestHealDriveCounts(t *testing.T) {
 	rs := HealResultItem{}
@@ -70,3 +126,1109 @@ func TestHealDriveCounts(t *testing.T) {
 		t.Errorf("Expected '4', got %d after missing disks", i)
 	}
 }
+
+// Tests heal backlog computation with healing and non healing disks.
+func TestSetStatusBacklog(t *testing.T) {
+	state := BgHealState{
+		Sets: []SetStatus{
+			{
+				ID:           "pool-0-set-0",
+				TotalObjects: 100,
+				Disks: []Disk{
+					{HealInfo: &HealingDisk{ItemsHealed: 40}},
+					{State: DriveStateOk},
+					{State: DriveStateOffline},
+				},
+			},
+			{
+				ID:           "pool-0-set-1",
+				TotalObjects: 100,
+				Disks: []Disk{
+					{HealInfo: &HealingDisk{ItemsHealed: 10}},
+					{HealInfo: &HealingDisk{ItemsHealed: 40}},
+					{HealInfo: &HealingDisk{ItemsHealed: 120}},
+				},
+			},
+			{
+				ID:           "pool-1-set-0",
+				TotalObjects: 50,
+				Disks:        []Disk{{State: DriveStateOk}},
+			},
+		},
+	}
+
+	expected := map[string]int{
+		"pool-0-set-0": 60,
+		"pool-0-set-1": 90,
+	}
+	backlogs := state.Backlogs()
+	if !reflect.DeepEqual(backlogs, expected) {
+		t.Errorf("Expected %v, got %v", expected, backlogs)
+	}
+	if backlog, ok := state.Sets[2].Backlog(); ok || backlog != 0 {
+		t.Errorf("Expected unknown backlog, got %d", backlog)
+	}
+	state.Sets[1].Disks = state.Sets[1].Disks[2:]
+	if backlog, ok := state.Sets[1].Backlog(); !ok || backlog != 0 {
+		t.Errorf("Expected known empty backlog, got %d (%v)", backlog, ok)
+	}
+}
+
+// Tests HealStartIfAbsent reusing a running sequence and starting a
+// fresh one.
+func TestHealStartIfAbsent(t *testing.T) {
+	opts := HealOpts{Recursive: true, ScanMode: HealNormalScan}
+	var starts int
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case libraryAdminURLPrefix + adminAPIPrefix + "/heal-sequences":
+			json.NewEncoder(w).Encode([]HealSequenceInfo{
+				{ClientToken: "finished", Bucket: "bucket", Prefix: "a", Summary: HealFinishedState, HealSettings: opts},
+				{ClientToken: "running", Bucket: "bucket", Prefix: "a", Summary: HealRunningState, HealSettings: opts},
+			})
+		default:
+			starts++
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "new"})
+		}
+	})
+
+	healStart, started, err := adm.HealStartIfAbsent(context.Background(), "bucket", "a", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started || healStart.ClientToken != "running" {
+		t.Errorf("Expected running sequence to be reused, got %v %v", healStart.ClientToken, started)
+	}
+
+	healStart, started, err = adm.HealStartIfAbsent(context.Background(), "bucket", "b", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !started || healStart.ClientToken != "new" {
+		t.Errorf("Expected new sequence to be started, got %v %v", healStart.ClientToken, started)
+	}
+	if starts != 1 {
+		t.Errorf("Expected 1 heal start, got %d", starts)
+	}
+}
+
+// Tests MergeChan produces the same result as a batch Merge.
+func TestBgHealStateMergeChan(t *testing.T) {
+	states := []BgHealState{
+		{
+			ScannedItemsCount: 10,
+			SCParity:          map[string]int{"STANDARD": 4},
+			Sets:              []SetStatus{{ID: "pool-0-set-1", SetIndex: 1, Disks: []Disk{{}, {}}}},
+		},
+		{
+			ScannedItemsCount: 20,
+			OfflineEndpoints:  []string{"http://server2/disk1"},
+			MRF:               map[string]MRFStatus{"server2": {ItemsHealed: 5}},
+			Sets:              []SetStatus{{ID: "pool-0-set-0", Disks: []Disk{{}, {HealInfo: &HealingDisk{ID: "disk"}}}}},
+		},
+		{
+			ScannedItemsCount: 30,
+			Sets:              []SetStatus{{ID: "pool-0-set-1", SetIndex: 1, Disks: []Disk{{HealInfo: &HealingDisk{ID: "disk"}}, {}}}},
+		},
+	}
+
+	var batch BgHealState
+	batch.Merge(states...)
+
+	in := make(chan BgHealState)
+	go func() {
+		defer close(in)
+		for _, state := range states {
+			in <- state
+		}
+	}()
+	var merged BgHealState
+	merged.MergeChan(context.Background(), in)
+
+	if !reflect.DeepEqual(batch, merged) {
+		t.Errorf("Expected %+v, got %+v", batch, merged)
+	}
+}
+
+// Tests MergeWithConflicts reports sets with different totals.
+func TestBgHealStateMergeWithConflicts(t *testing.T) {
+	states := []BgHealState{
+		{SCParity: map[string]int{"STANDARD": 4}, Sets: []SetStatus{{ID: "pool-0-set-0", TotalObjects: 100}}},
+		{SCParity: map[string]int{"STANDARD": 4}, Sets: []SetStatus{{ID: "pool-0-set-0", TotalObjects: 100}}},
+		{SCParity: map[string]int{"STANDARD": 4}, Sets: []SetStatus{{ID: "pool-0-set-0", TotalObjects: 90}}},
+	}
+
+	var merged BgHealState
+	conflicts := merged.MergeWithConflicts(states...)
+	expected := []MergeConflict{{SetID: "pool-0-set-0", Field: "TotalObjects", Existing: "100", Other: "90"}}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, conflicts)
+	}
+
+	var plain BgHealState
+	plain.Merge(states...)
+	if !reflect.DeepEqual(merged, plain) {
+		t.Errorf("Expected %+v, got %+v", plain, merged)
+	}
+
+	if conflicts = new(BgHealState).MergeWithConflicts(states[:2]...); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+}
+
+// Tests BackgroundHealStatusPool with scoping and older servers.
+func TestBackgroundHealStatusPool(t *testing.T) {
+	all := BgHealState{
+		Decommissioning: []int{0},
+		Sets: []SetStatus{
+			{ID: "pool-0-set-0", PoolIndex: 0},
+			{ID: "pool-1-set-0", PoolIndex: 1},
+			{ID: "pool-1-set-1", PoolIndex: 1, SetIndex: 1},
+		},
+	}
+	for _, scoped := range []bool{true, false} {
+		adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+			pool := r.URL.Query().Get("pool")
+			if pool != "1" {
+				t.Errorf("Expected pool 1, got %q", pool)
+			}
+			state := all
+			if scoped {
+				state.Decommissioning = nil
+				state.Sets = all.Sets[1:]
+			}
+			json.NewEncoder(w).Encode(state)
+		})
+
+		state, err := adm.BackgroundHealStatusPool(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(state.Sets) != 2 || state.Sets[0].ID != "pool-1-set-0" || state.Sets[1].ID != "pool-1-set-1" {
+			t.Errorf("Scoped %v: Unexpected sets %+v", scoped, state.Sets)
+		}
+		if len(state.Decommissioning) != 0 {
+			t.Errorf("Scoped %v: Unexpected decommissioning pools %v", scoped, state.Decommissioning)
+		}
+	}
+
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := adm.BackgroundHealStatusPool(context.Background(), -1); err == nil {
+		t.Error("Expected error for negative pool index")
+	}
+}
+
+// Tests OldestHealAge picks the earliest active sequence.
+func TestOldestHealAge(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	sequences := []HealSequenceInfo{
+		{ClientToken: "recent", StartTime: now.Add(-time.Minute), Summary: HealRunningState},
+		{ClientToken: "old", StartTime: now.Add(-time.Hour), Summary: HealRunningState},
+		{ClientToken: "finished", StartTime: now.Add(-24 * time.Hour), Summary: HealFinishedState},
+	}
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(sequences)
+	})
+	adm.SetClock(func() time.Time { return now })
+
+	age, token, err := adm.OldestHealAge(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "old" || age != time.Hour {
+		t.Errorf("Expected old sequence of an hour, got %q of %v", token, age)
+	}
+
+	sequences = sequences[2:]
+	if age, token, err = adm.OldestHealAge(context.Background()); err != nil || age != 0 || token != "" {
+		t.Errorf("Expected no active sequence, got %q of %v (%v)", token, age, err)
+	}
+}
+
+// Tests StorageClassParity falls back to the background heal status.
+func TestStorageClassParity(t *testing.T) {
+	state := BgHealState{SCParity: map[string]int{"STANDARD": 4, "reduced_redundancy": 2}}
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/storage-class/parity") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(state)
+	})
+
+	parity, err := adm.StorageClassParity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"STANDARD": 4, "REDUCED_REDUNDANCY": 2}
+	if !reflect.DeepEqual(parity, expected) {
+		t.Errorf("Expected %v, got %v", expected, parity)
+	}
+
+	state.SCParity = map[string]int{"STANDARD": 0}
+	if _, err = adm.StorageClassParity(context.Background()); err == nil {
+		t.Error("Expected error for zero parity")
+	}
+	state.SCParity = map[string]int{"STANDARD": 4, "standard": 2}
+	if _, err = adm.StorageClassParity(context.Background()); err == nil {
+		t.Error("Expected error for conflicting parities")
+	}
+}
+
+// Tests empty success responses are reported with ErrEmptyResponse.
+func TestHealEmptyResponse(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if _, err := adm.BackgroundHealStatus(context.Background()); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Expected ErrEmptyResponse, got %v", err)
+	}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", HealOpts{}, "", false, false); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Expected ErrEmptyResponse, got %v", err)
+	}
+	if _, err := adm.HealStatus(context.Background(), "bucket", "", "token"); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Expected ErrEmptyResponse, got %v", err)
+	}
+}
+
+// Tests HealManyWeighted gives more concurrent slots to heavier jobs.
+func TestHealManyWeighted(t *testing.T) {
+	defer func(n int, interval time.Duration) {
+		MaxWeightedHealConcurrency, healManyPollInterval = n, interval
+	}(MaxWeightedHealConcurrency, healManyPollInterval)
+	MaxWeightedHealConcurrency, healManyPollInterval = 4, time.Millisecond
+
+	var (
+		mu      sync.Mutex
+		active  = make(map[string]int)
+		peak    = make(map[string]int)
+		polls   = make(map[string]int)
+		started []string
+	)
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		bucket := strings.Split(strings.TrimPrefix(r.URL.Path, libraryAdminURLPrefix+adminAPIPrefix+"/heal/"), "/")[0]
+		token := r.URL.Query().Get("clientToken")
+
+		mu.Lock()
+		defer mu.Unlock()
+		if token == "" {
+			started = append(started, bucket)
+			active[bucket]++
+			if active[bucket] > peak[bucket] {
+				peak[bucket] = active[bucket]
+			}
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: r.URL.Path})
+			return
+		}
+		polls[token]++
+		if polls[token] < 5 {
+			json.NewEncoder(w).Encode(HealTaskStatus{Summary: string(HealRunningState)})
+			return
+		}
+		if polls[token] == 5 {
+			active[bucket]--
+		}
+		json.NewEncoder(w).Encode(HealTaskStatus{Summary: string(HealFinishedState)})
+	})
+
+	var jobs []WeightedHealJob
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, WeightedHealJob{Bucket: "bulk", Prefix: strconv.Itoa(i), Weight: 1})
+		jobs = append(jobs, WeightedHealJob{Bucket: "critical", Prefix: strconv.Itoa(i), Weight: 3})
+	}
+	resultCh, err := adm.HealManyWeighted(context.Background(), jobs, HealOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results int
+	for result := range resultCh {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		results++
+	}
+
+	if results != len(jobs) {
+		t.Errorf("Expected %d results, got %d", len(jobs), results)
+	}
+	first := map[string]int{}
+	for _, bucket := range started[:4] {
+		first[bucket]++
+	}
+	if first["critical"] != 3 || first["bulk"] != 1 {
+		t.Errorf("Expected 3 critical and 1 bulk slots, got %v", first)
+	}
+	if peak["critical"] != 3 {
+		t.Errorf("Expected at most 3 concurrent critical heals, got %d", peak["critical"])
+	}
+
+	if _, err = adm.HealManyWeighted(context.Background(), []WeightedHealJob{{Bucket: "bucket"}}, HealOpts{}); err == nil {
+		t.Error("Expected error for zero weight")
+	}
+}
+
+// Tests StopSetHeal sends the set to stop and validates it exists.
+func TestStopSetHeal(t *testing.T) {
+	var body []byte
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case libraryAdminURLPrefix + adminAPIPrefix + "/background-heal/status":
+			json.NewEncoder(w).Encode(BgHealState{Sets: []SetStatus{{PoolIndex: 1, SetIndex: 2}}})
+		case libraryAdminURLPrefix + adminAPIPrefix + "/background-heal/stop-set":
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected POST, got %s", r.Method)
+			}
+			body, _ = ioutil.ReadAll(r.Body)
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	})
+
+	if err := adm.StopSetHeal(context.Background(), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"pool":1,"set":2}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+
+	body = nil
+	if err := adm.StopSetHeal(context.Background(), 1, 3); err == nil {
+		t.Error("Expected error for unknown set")
+	}
+	if err := adm.StopSetHeal(context.Background(), -1, 0); err == nil {
+		t.Error("Expected error for negative pool index")
+	}
+	if body != nil {
+		t.Error("Expected no stop request for invalid sets")
+	}
+}
+
+// Tests HealStatus distinguishes unknown and finished sequences.
+func TestHealStatus(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("clientToken") {
+		case "finished":
+			json.NewEncoder(w).Encode(HealTaskStatus{Summary: string(HealFinishedState)})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Code: "XMinioHealNoSuchProcess", Message: "No such heal process is running on the server"})
+		}
+	})
+
+	status, err := adm.HealStatus(context.Background(), "bucket", "", "finished")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State() != HealFinishedState {
+		t.Errorf("Expected %q, got %q", HealFinishedState, status.State())
+	}
+
+	_, err = adm.HealStatus(context.Background(), "bucket", "", "unknown")
+	if !errors.Is(err, ErrHealNotStarted) {
+		t.Errorf("Expected ErrHealNotStarted, got %v", err)
+	}
+}
+
+// Tests decoding of heal responses carrying a status, an error or an
+// unexpected body.
+func TestDecodeHealResponse(t *testing.T) {
+	testCases := []struct {
+		body     string
+		expected HealTaskStatus
+		errCode  string
+	}{
+		{body: `{"summary":"finished","detail":""}`, expected: HealTaskStatus{Summary: "finished"}},
+		{body: `{"Code":"XMinioHealInternalError","Message":"heal failed"}`, errCode: "XMinioHealInternalError"},
+		{body: `{"summary":["unexpected"]}`, errCode: "UnexpectedResponse"},
+		{body: `not json`, errCode: "UnexpectedResponse"},
+	}
+
+	for i, testCase := range testCases {
+		var status HealTaskStatus
+		err := decodeHealResponse([]byte(testCase.body), &status, false)
+		if testCase.errCode != "" {
+			if code := ToErrorResponse(err).Code; code != testCase.errCode {
+				t.Errorf("Test %d: expected error code %q, got %v", i+1, testCase.errCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i+1, err)
+		}
+		if !reflect.DeepEqual(status, testCase.expected) {
+			t.Errorf("Test %d: expected %+v, got %+v", i+1, testCase.expected, status)
+		}
+	}
+}
+
+// Tests pausing and resuming background heal.
+func TestPauseResumeBackgroundHeal(t *testing.T) {
+	var paths []string
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case libraryAdminURLPrefix + adminAPIPrefix + "/background-heal/status":
+			w.Write([]byte(`{"paused":true}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	ctx := context.Background()
+	if err := adm.PauseBackgroundHeal(ctx); err != nil {
+		t.Fatal(err)
+	}
+	state, err := adm.BackgroundHealStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Paused {
+		t.Error("Expected background heal to be paused")
+	}
+	if err = adm.ResumeBackgroundHeal(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := libraryAdminURLPrefix + adminAPIPrefix + "/background-heal/"
+	expected := []string{prefix + "pause", prefix + "status", prefix + "resume"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+
+	old := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	var notSupported NotSupportedError
+	if err = old.PauseBackgroundHeal(ctx); !errors.As(err, &notSupported) {
+		t.Errorf("Expected NotSupportedError, got %v", err)
+	}
+}
+
+// Tests encoding and validation of heal throttling options.
+func TestHealThrottle(t *testing.T) {
+	var query url.Values
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+	})
+
+	opts := HealOpts{Throttle: &HealThrottle{MaxBytesPerSec: 10 << 20, MaxConcurrent: 4}}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("maxBytesPerSec") != "10485760" || query.Get("maxConcurrent") != "4" {
+		t.Errorf("Unexpected query %v", query)
+	}
+
+	opts.Throttle.MaxConcurrent = -1
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, false); err == nil {
+		t.Error("Expected error for negative max concurrent")
+	}
+
+	if opts.Equal(HealOpts{}) {
+		t.Error("Expected throttled options to differ from unthrottled ones")
+	}
+	if !opts.Equal(HealOpts{Throttle: &HealThrottle{MaxBytesPerSec: 10 << 20, MaxConcurrent: -1}}) {
+		t.Error("Expected identical throttles to be equal")
+	}
+}
+
+// Tests encoding and validation of the heal modification time window.
+func TestHealModifiedWindow(t *testing.T) {
+	after := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	before := after.Add(time.Hour)
+
+	v := make(url.Values)
+	opts := HealOpts{ModifiedAfter: &after, ModifiedBefore: &before}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.setQueryValues(v)
+	if v.Get("modifiedAfter") != "2021-07-01T10:00:00Z" || v.Get("modifiedBefore") != "2021-07-01T11:00:00Z" {
+		t.Errorf("Unexpected query %v", v)
+	}
+
+	if opts.Equal(HealOpts{ModifiedAfter: &after}) {
+		t.Error("Expected different time windows to not be equal")
+	}
+	sameAfter := after.In(time.Local)
+	if !opts.Equal(HealOpts{ModifiedAfter: &sameAfter, ModifiedBefore: &before}) {
+		t.Error("Expected same time windows to be equal")
+	}
+
+	invalid := HealOpts{ModifiedAfter: &before, ModifiedBefore: &after}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected error for inverted time window")
+	}
+}
+
+// Tests encoding and comparison of the expected topology.
+func TestHealExpectTopology(t *testing.T) {
+	disks, sets := 16, 4
+
+	v := make(url.Values)
+	opts := HealOpts{ExpectDiskCount: &disks, ExpectSetCount: &sets}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.setQueryValues(v)
+	if v.Get("expectDiskCount") != "16" || v.Get("expectSetCount") != "4" {
+		t.Errorf("Unexpected query %v", v)
+	}
+
+	v = make(url.Values)
+	HealOpts{}.setQueryValues(v)
+	if _, ok := v["expectDiskCount"]; ok {
+		t.Errorf("Unexpected query %v", v)
+	}
+
+	sameDisks, otherSets := 16, 8
+	if !opts.Equal(HealOpts{ExpectDiskCount: &sameDisks, ExpectSetCount: &sets}) {
+		t.Error("Expected same topologies to be equal")
+	}
+	if opts.Equal(HealOpts{ExpectDiskCount: &disks, ExpectSetCount: &otherSets}) {
+		t.Error("Expected different topologies to not be equal")
+	}
+	if opts.Equal(HealOpts{ExpectDiskCount: &disks}) {
+		t.Error("Expected unset topology to not be equal")
+	}
+
+	zero := 0
+	if err := (HealOpts{ExpectSetCount: &zero}).Validate(); err == nil {
+		t.Error("Expected error for zero expected set count")
+	}
+}
+
+// Tests the drive endpoint is validated and sent.
+func TestHealEndpoint(t *testing.T) {
+	var query url.Values
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+	})
+
+	opts := HealOpts{Endpoint: "http://server1:9000/data1"}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("endpoint") != opts.Endpoint {
+		t.Errorf("Unexpected query %v", query)
+	}
+
+	testCases := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"http://server1:9000/data1", true},
+		{"https://server1/mnt/data1", true},
+		{"/data1", true},
+		{"server1:9000/data1", false},
+		{"http://server1:9000", false},
+		{"ftp://server1/data1", false},
+		{"data1", false},
+	}
+	for i, testCase := range testCases {
+		err := HealOpts{Endpoint: testCase.endpoint}.Validate()
+		if (err == nil) != testCase.valid {
+			t.Errorf("Test %d: Unexpected validation result for %q: %v", i+1, testCase.endpoint, err)
+		}
+	}
+}
+
+// Tests the metadata only option is sent and can't be deep.
+func TestHealMetadataOnly(t *testing.T) {
+	v := make(url.Values)
+	opts := HealOpts{MetadataOnly: true, ScanMode: HealNormalScan}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.setQueryValues(v)
+	if v.Get("metadataOnly") != "true" {
+		t.Errorf("Unexpected query %v", v)
+	}
+	if opts.Equal(HealOpts{ScanMode: HealNormalScan}) {
+		t.Error("Expected metadata only heal to differ from full heal")
+	}
+
+	opts.ScanMode = HealDeepScan
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for metadata only deep scan")
+	}
+}
+
+// Tests the idempotency key is sent as a header on starts only.
+func TestHealIdempotencyKey(t *testing.T) {
+	var starts int
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.URL.Query().Get("clientToken") != "" {
+			if key != "" {
+				t.Errorf("Unexpected idempotency key %q on status request", key)
+			}
+			json.NewEncoder(w).Encode(HealTaskStatus{Summary: string(HealRunningState)})
+			return
+		}
+		if r.URL.Query().Get("forceStop") == "true" || r.URL.Query().Get("forceStart") == "true" {
+			if key != "" {
+				t.Errorf("Unexpected idempotency key %q on forced request %s", key, r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+			return
+		}
+		if key != "job-42" {
+			t.Errorf("Expected idempotency key job-42, got %q", key)
+		}
+		if strings.Contains(r.URL.RawQuery, "job-42") {
+			t.Errorf("Unexpected idempotency key in query %s", r.URL.RawQuery)
+		}
+		starts++
+		// The server returns the existing sequence for the same key.
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+	})
+
+	opts := HealOpts{IdempotencyKey: "job-42"}
+	for i := 0; i < 2; i++ {
+		healStart, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if healStart.ClientToken != "token" {
+			t.Errorf("Unexpected client token %q", healStart.ClientToken)
+		}
+	}
+	if _, err := adm.HealStatus(context.Background(), "bucket", "", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if starts != 2 {
+		t.Errorf("Expected 2 starts, got %d", starts)
+	}
+}
+
+// Tests delete markers can be included in heals and reported.
+func TestHealDeleteMarkers(t *testing.T) {
+	v := make(url.Values)
+	opts := HealOpts{IncludeDeleteMarkers: true}
+	opts.setQueryValues(v)
+	if v.Get("includeDeleteMarkers") != "true" {
+		t.Errorf("Unexpected query %v", v)
+	}
+	if opts.Equal(HealOpts{}) {
+		t.Error("Expected heals including delete markers to differ")
+	}
+
+	item := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: "object", VersionID: "v1", IsDeleteMarker: true}
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded HealResultItem
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, item) {
+		t.Errorf("Expected %+v, got %+v", item, decoded)
+	}
+
+	var older HealResultItem
+	if err = json.Unmarshal([]byte(`{"type":"object","versionId":"v2"}`), &older); err != nil {
+		t.Fatal(err)
+	}
+	if older.IsDeleteMarker {
+		t.Error("Expected items of older servers to not be delete markers")
+	}
+}
+
+// Tests encoding and validation of the diagnostic read quorum.
+func TestHealReadQuorum(t *testing.T) {
+	quorum, disks := 3, 4
+
+	v := make(url.Values)
+	opts := HealOpts{DryRun: true, ReadQuorum: &quorum, ExpectDiskCount: &disks}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.setQueryValues(v)
+	if v.Get("readQuorum") != "3" {
+		t.Errorf("Unexpected query %v", v)
+	}
+
+	invalid := []HealOpts{
+		{ReadQuorum: &quorum},
+		{DryRun: true, ReadQuorum: new(int)},
+		{DryRun: true, ReadQuorum: &disks, ExpectDiskCount: &quorum},
+	}
+	for i, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Test %d: Expected validation error", i+1)
+		}
+	}
+}
+
+// Tests HealStopByPrefix force stops the matching sequence.
+func TestHealStopByPrefix(t *testing.T) {
+	var stops []string
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case libraryAdminURLPrefix + adminAPIPrefix + "/heal-sequences":
+			json.NewEncoder(w).Encode([]HealSequenceInfo{
+				{ClientToken: "other", Bucket: "bucket", Prefix: "b", Summary: HealRunningState},
+				{ClientToken: "token", Bucket: "bucket", Prefix: "a", Summary: HealRunningState},
+			})
+		default:
+			if r.URL.Query().Get("forceStop") == "true" {
+				stops = append(stops, r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+		}
+	})
+
+	if err := adm.HealStopByPrefix(context.Background(), "bucket", "a"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{libraryAdminURLPrefix + adminAPIPrefix + "/heal/bucket/a"}
+	if !reflect.DeepEqual(stops, expected) {
+		t.Errorf("Expected %v, got %v", expected, stops)
+	}
+
+	if err := adm.HealStopByPrefix(context.Background(), "bucket", "c"); !errors.Is(err, ErrHealNotStarted) {
+		t.Errorf("Expected ErrHealNotStarted, got %v", err)
+	}
+}
+
+// Tests decoding numeric and string encoded heal counters.
+func TestHealCountersDecoding(t *testing.T) {
+	for _, body := range []string{
+		`{"id":"disk","bytes_done":18446744073709551615,"items_healed":10}`,
+		`{"id":"disk","bytes_done":"18446744073709551615","items_healed":"10"}`,
+	} {
+		var h HealingDisk
+		if err := json.Unmarshal([]byte(body), &h); err != nil {
+			t.Fatal(err)
+		}
+		if h.ID != "disk" || h.BytesDone != 18446744073709551615 || h.ItemsHealed != 10 {
+			t.Errorf("Unexpected healing disk %+v decoded from %s", h, body)
+		}
+	}
+
+	for _, body := range []string{
+		`{"total_bytes":1099511627776,"items_healed":3}`,
+		`{"total_bytes":"1099511627776","items_healed":"3"}`,
+	} {
+		var m MRFStatus
+		if err := json.Unmarshal([]byte(body), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.TotalBytes != 1099511627776 || m.ItemsHealed != 3 {
+			t.Errorf("Unexpected MRF status %+v decoded from %s", m, body)
+		}
+	}
+
+	b, err := json.Marshal(HealingDisk{BytesDone: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"bytes_done":42`) {
+		t.Errorf("Expected numeric encoding, got %s", b)
+	}
+
+	var h HealingDisk
+	if err = json.Unmarshal([]byte(`{"bytes_done":"bad"}`), &h); err == nil {
+		t.Error("Expected error for invalid counter")
+	}
+}
+
+// Tests SettingsMatch detects a downgraded scan mode.
+func TestHealTaskStatusSettingsMatch(t *testing.T) {
+	requested := HealOpts{Recursive: true, ScanMode: HealDeepScan}
+	status := HealTaskStatus{HealSettings: HealOpts{Recursive: true, ScanMode: HealNormalScan}}
+
+	ok, fields := status.SettingsMatch(requested)
+	if ok || !reflect.DeepEqual(fields, []string{"ScanMode"}) {
+		t.Errorf("Expected ScanMode mismatch, got %v %v", ok, fields)
+	}
+
+	status.HealSettings = requested
+	if ok, fields = status.SettingsMatch(requested); !ok || len(fields) != 0 {
+		t.Errorf("Expected settings to match, got %v", fields)
+	}
+}
+
+// Tests defaults applied to partially decoded heal options.
+func TestHealOptsApplyDefaults(t *testing.T) {
+	var opts HealOpts
+	if err := json.Unmarshal([]byte(`{"recursive":true}`), &opts); err != nil {
+		t.Fatal(err)
+	}
+	opts.ApplyDefaults()
+	expected := HealOpts{Recursive: true, ScanMode: HealNormalScan}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, opts)
+	}
+
+	opts = HealOpts{ScanMode: HealDeepScan}
+	opts.ApplyDefaults()
+	if opts.ScanMode != HealDeepScan {
+		t.Errorf("Expected deep scan to be kept, got %v", opts.ScanMode)
+	}
+}
+
+// Tests the heal owner is sent and surfaced on status and sequences.
+func TestHealOwner(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == libraryAdminURLPrefix+adminAPIPrefix+"/heal-sequences":
+			json.NewEncoder(w).Encode([]HealSequenceInfo{
+				{ClientToken: "mine", HealSettings: HealOpts{Owner: "tenant-a"}},
+				{ClientToken: "other", HealSettings: HealOpts{Owner: "tenant-b"}},
+			})
+		case r.URL.Query().Get("clientToken") != "":
+			json.NewEncoder(w).Encode(HealTaskStatus{HealSettings: HealOpts{Owner: "tenant-a"}})
+		default:
+			if owner := r.URL.Query().Get("owner"); owner != "tenant-a" {
+				t.Errorf("Expected owner tenant-a, got %q", owner)
+			}
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "mine"})
+		}
+	})
+
+	ctx := context.Background()
+	healStart, _, err := adm.Heal(ctx, "bucket", "", HealOpts{Owner: "tenant-a"}, "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := adm.HealStatus(ctx, "bucket", "", healStart.ClientToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.HealSettings.Owner != "tenant-a" {
+		t.Errorf("Expected owner on status, got %q", status.HealSettings.Owner)
+	}
+
+	sequences, err := adm.ListHealSequencesByOwner(ctx, "tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != 1 || sequences[0].ClientToken != "mine" {
+		t.Errorf("Unexpected sequences %+v", sequences)
+	}
+}
+
+// Tests unknown response fields are only rejected with strict decoding.
+func TestStrictDecoding(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/background-heal/status") {
+			w.Write([]byte(`{"offline_nodes":[],"sets":[],"newField":1}`))
+			return
+		}
+		w.Write([]byte(`{"clientToken":"token","newStartField":true}`))
+	})
+
+	ctx := context.Background()
+	if _, err := adm.BackgroundHealStatus(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := adm.Heal(ctx, "bucket", "", HealOpts{}, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	adm.SetStrictDecoding(true)
+	if _, err := adm.BackgroundHealStatus(ctx); err == nil || !strings.Contains(err.Error(), `"newField"`) {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+	if _, _, err := adm.Heal(ctx, "bucket", "", HealOpts{}, "", false, false); err == nil || !strings.Contains(err.Error(), `"newStartField"`) {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+}
+
+// Tests the JSON encoding of HealStartSuccess with and without the
+// server node.
+func TestHealStartSuccessJSON(t *testing.T) {
+	startTime := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		start HealStartSuccess
+		json  string
+	}{
+		{
+			start: HealStartSuccess{ClientToken: "token", ClientAddress: "10.0.0.1", StartTime: startTime},
+			json:  `{"clientToken":"token","clientAddress":"10.0.0.1","startTime":"2021-07-01T10:00:00Z"}`,
+		},
+		{
+			start: HealStartSuccess{ClientToken: "token", ClientAddress: "10.0.0.1", StartTime: startTime, ServerNode: "server1:9000"},
+			json:  `{"clientToken":"token","clientAddress":"10.0.0.1","startTime":"2021-07-01T10:00:00Z","serverNode":"server1:9000"}`,
+		},
+	}
+	for i, testCase := range testCases {
+		data, err := json.Marshal(testCase.start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != testCase.json {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.json, data)
+		}
+		var start HealStartSuccess
+		if err = json.Unmarshal([]byte(testCase.json), &start); err != nil {
+			t.Fatal(err)
+		}
+		if start != testCase.start {
+			t.Errorf("Test %d: Expected %+v, got %+v", i+1, testCase.start, start)
+		}
+	}
+}
+
+// Tests HealCapabilities parses the capabilities of the server and
+// reports none for older servers.
+func TestHealCapabilities(t *testing.T) {
+	supported := true
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/heal-capabilities") {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !supported {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"perPool":true,"throttle":false,"metadataOnly":true,"deleteMarkers":true}`))
+	})
+
+	caps, err := adm.HealCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := HealCapabilities{PerPool: true, MetadataOnly: true, DeleteMarkers: true}
+	if caps != expected {
+		t.Errorf("Expected %+v, got %+v", expected, caps)
+	}
+
+	supported = false
+	caps, err = adm.HealCapabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps != (HealCapabilities{}) {
+		t.Errorf("Expected no capabilities, got %+v", caps)
+	}
+}
+
+// Tests encoding and validation of the heal priority.
+func TestHealPriority(t *testing.T) {
+	opts := HealOpts{Priority: HealPriorityHigh}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	v := make(url.Values)
+	opts.setQueryValues(v)
+	if v.Get("priority") != "high" {
+		t.Errorf("Unexpected query %v", v)
+	}
+	if opts.Equal(HealOpts{Priority: HealPriorityLow}) {
+		t.Error("Expected options with different priorities to differ")
+	}
+
+	v = make(url.Values)
+	HealOpts{}.setQueryValues(v)
+	if _, ok := v["priority"]; ok {
+		t.Errorf("Expected no priority, got %v", v)
+	}
+
+	if err := (HealOpts{Priority: "urgent"}).Validate(); err == nil {
+		t.Error("Expected error for unknown priority")
+	}
+}
+
+// Tests the latest version only option is sent to the server and
+// rejected for heals which don't heal objects.
+func TestHealLatestVersionOnly(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("latestVersionOnly") != "true" {
+			t.Errorf("Expected latestVersionOnly, got %v", r.URL.Query())
+		}
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+	})
+
+	opts := HealOpts{LatestVersionOnly: true}
+	if _, _, err := adm.Heal(context.Background(), "bucket", "object", opts, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Equal(HealOpts{}) {
+		t.Error("Expected options to differ")
+	}
+
+	if _, _, err := adm.Heal(context.Background(), "bucket", "", opts, "", false, false); err == nil {
+		t.Error("Expected error for a non recursive bucket heal")
+	}
+	opts.VersionID = "v1"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error with a version ID")
+	}
+}
+
+// Tests ValidateAgainst reports each option the server can't handle.
+func TestHealOptsValidateAgainst(t *testing.T) {
+	caps := HealCapabilities{Throttle: true}
+	testCases := []struct {
+		opts        HealOpts
+		unsupported []string
+	}{
+		{opts: HealOpts{Recursive: true}},
+		{opts: HealOpts{Throttle: &HealThrottle{MaxConcurrent: 2}}},
+		{opts: HealOpts{MetadataOnly: true}, unsupported: []string{"MetadataOnly"}},
+		{opts: HealOpts{MetadataOnly: true, IncludeDeleteMarkers: true}, unsupported: []string{"MetadataOnly", "IncludeDeleteMarkers"}},
+	}
+	for i, testCase := range testCases {
+		err := testCase.opts.ValidateAgainst(caps)
+		if len(testCase.unsupported) == 0 {
+			if err != nil {
+				t.Errorf("Test %d: Unexpected error %v", i+1, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("Test %d: Expected error", i+1)
+			continue
+		}
+		for _, field := range testCase.unsupported {
+			if !strings.Contains(err.Error(), field) {
+				t.Errorf("Test %d: Expected %s in %v", i+1, field, err)
+			}
+		}
+	}
+}
+
+// Tests the scan progress is decoded when present and zero otherwise.
+func TestHealTaskStatusProgress(t *testing.T) {
+	testCases := []struct {
+		json    string
+		percent float64
+	}{
+		{json: `{"summary":"running","detail":"","startTime":"2021-07-01T10:00:00Z","settings":{}}`, percent: 0},
+		{json: `{"summary":"running","detail":"","startTime":"2021-07-01T10:00:00Z","settings":{},"progress":42.5}`, percent: 42.5},
+		{json: `{"summary":"finished","detail":"","startTime":"2021-07-01T10:00:00Z","settings":{},"progress":100.2}`, percent: 100},
+	}
+	for i, testCase := range testCases {
+		var status HealTaskStatus
+		if err := json.Unmarshal([]byte(testCase.json), &status); err != nil {
+			t.Fatal(err)
+		}
+		if percent := status.Percent(); percent != testCase.percent {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.percent, percent)
+		}
+	}
+
+	data, err := json.Marshal(HealTaskStatus{Summary: "running"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "progress") {
+		t.Errorf("Expected no progress for older servers, got %s", data)
+	}
+}
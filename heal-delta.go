@@ -0,0 +1,200 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// HealingDiskDelta - progress made by a healing disk between two
+// background heal states, see DiffBgHealState. Counters are signed,
+// they decrease when the heal of the disk restarts.
+type HealingDiskDelta struct {
+	ID          string `json:"id"`
+	Endpoint    string `json:"endpoint"`
+	ItemsHealed int64  `json:"items_healed,omitempty"`
+	ItemsFailed int64  `json:"items_failed,omitempty"`
+	BytesDone   int64  `json:"bytes_done,omitempty"`
+	BytesFailed int64  `json:"bytes_failed,omitempty"`
+
+	// Added is true if the disk started healing, its counters are
+	// then the whole progress of the disk.
+	Added bool `json:"added,omitempty"`
+	// Removed is true if the disk is not healing anymore.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// BgHealDelta - changes between two background heal states, see
+// DiffBgHealState and AdminClient.BackgroundHealDeltas.
+type BgHealDelta struct {
+	// Baseline is the full state the following deltas apply to, it
+	// is only set on the first delta sent by BackgroundHealDeltas.
+	Baseline *BgHealState `json:"baseline,omitempty"`
+
+	// Disks is the progress of the healing disks which changed.
+	Disks []HealingDiskDelta `json:"disks,omitempty"`
+	// Sets are the sets whose heal status or drive health changed,
+	// as reported in the current state.
+	Sets []SetStatus `json:"sets,omitempty"`
+	// Offline and Online are the endpoints which went offline and
+	// came back online.
+	Offline []string `json:"offline,omitempty"`
+	Online  []string `json:"online,omitempty"`
+}
+
+// Empty returns true if the delta holds no change.
+func (d BgHealDelta) Empty() bool {
+	return d.Baseline == nil && len(d.Disks) == 0 && len(d.Sets) == 0 && len(d.Offline) == 0 && len(d.Online) == 0
+}
+
+// diffCounter returns the signed difference cur - prev.
+func diffCounter(prev, cur uint64) int64 {
+	return int64(cur) - int64(prev)
+}
+
+// setKey returns a key identifying the set, its ID when reported.
+func setKey(s SetStatus) string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return strconv.Itoa(s.PoolIndex) + "/" + strconv.Itoa(s.SetIndex)
+}
+
+// DiffBgHealState returns the changes from prev to cur: the progress of
+// the healing disks, matched by ID, the sets whose heal status or
+// drive health changed and the endpoints which went offline or came
+// back online.
+func DiffBgHealState(prev, cur BgHealState) BgHealDelta {
+	var delta BgHealDelta
+
+	prevDisks, curDisks := prev.healingDisks(), cur.healingDisks()
+	for id, disk := range curDisks {
+		d := HealingDiskDelta{ID: id, Endpoint: disk.Endpoint}
+		prevDisk, ok := prevDisks[id]
+		d.Added = !ok
+		d.ItemsHealed = diffCounter(prevDisk.ItemsHealed, disk.ItemsHealed)
+		d.ItemsFailed = diffCounter(prevDisk.ItemsFailed, disk.ItemsFailed)
+		d.BytesDone = diffCounter(prevDisk.BytesDone, disk.BytesDone)
+		d.BytesFailed = diffCounter(prevDisk.BytesFailed, disk.BytesFailed)
+		if d.Added || d.ItemsHealed != 0 || d.ItemsFailed != 0 || d.BytesDone != 0 || d.BytesFailed != 0 {
+			delta.Disks = append(delta.Disks, d)
+		}
+	}
+	for id, disk := range prevDisks {
+		if _, ok := curDisks[id]; !ok {
+			delta.Disks = append(delta.Disks, HealingDiskDelta{ID: id, Endpoint: disk.Endpoint, Removed: true})
+		}
+	}
+	sort.Slice(delta.Disks, func(i, j int) bool { return delta.Disks[i].ID < delta.Disks[j].ID })
+
+	prevSets := make(map[string]SetStatus, len(prev.Sets))
+	for _, set := range prev.Sets {
+		prevSets[setKey(set)] = set
+	}
+	for _, set := range cur.Sets {
+		prevSet, ok := prevSets[setKey(set)]
+		if ok && prevSet.HealStatus == set.HealStatus {
+			prevOnline, prevTotal := prevSet.DriveHealth()
+			online, total := set.DriveHealth()
+			if prevOnline == online && prevTotal == total {
+				continue
+			}
+		}
+		delta.Sets = append(delta.Sets, set.Clone())
+	}
+
+	wasOffline := make(map[string]bool, len(prev.OfflineEndpoints))
+	for _, endpoint := range prev.OfflineEndpoints {
+		wasOffline[endpoint] = true
+	}
+	isOffline := make(map[string]bool, len(cur.OfflineEndpoints))
+	for _, endpoint := range cur.OfflineEndpoints {
+		isOffline[endpoint] = true
+		if !wasOffline[endpoint] {
+			delta.Offline = append(delta.Offline, endpoint)
+		}
+	}
+	for _, endpoint := range prev.OfflineEndpoints {
+		if !isOffline[endpoint] {
+			delta.Online = append(delta.Online, endpoint)
+		}
+	}
+	sort.Strings(delta.Offline)
+	sort.Strings(delta.Online)
+	return delta
+}
+
+// BackgroundHealDeltas - polls the background heal status every poll
+// and sends the changes between consecutive states on the returned
+// channel, see DiffBgHealState. The first delta holds the full state
+// as its Baseline, polls without changes send nothing. The delta
+// channel is closed when ctx is canceled or a poll fails, after which
+// the error channel receives the error before being closed. A poll
+// interval which is not positive is reported on the error channel with
+// an ErrInvalidArgument error.
+func (adm *AdminClient) BackgroundHealDeltas(ctx context.Context, poll time.Duration) (<-chan BgHealDelta, <-chan error) {
+	deltaCh := make(chan BgHealDelta)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		err := adm.pollBackgroundHealDeltas(ctx, poll, deltaCh)
+		close(deltaCh)
+		errCh <- err
+	}()
+	return deltaCh, errCh
+}
+
+func (adm *AdminClient) pollBackgroundHealDeltas(ctx context.Context, poll time.Duration, deltaCh chan<- BgHealDelta) error {
+	if poll <= 0 {
+		return ErrInvalidArgument("heal poll interval must be positive")
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var last *BgHealState
+	for {
+		state, err := adm.BackgroundHealStatus(ctx)
+		if err != nil {
+			return err
+		}
+		var delta BgHealDelta
+		if last == nil {
+			baseline := state.Clone()
+			delta.Baseline = &baseline
+		} else {
+			delta = DiffBgHealState(*last, state)
+		}
+		last = &state
+
+		if !delta.Empty() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case deltaCh <- delta:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
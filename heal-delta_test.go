@@ -0,0 +1,111 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests BackgroundHealDeltas sends the baseline followed by the changes
+// between two snapshots.
+func TestBackgroundHealDeltas(t *testing.T) {
+	prev := BgHealState{
+		OfflineEndpoints: []string{"server3:9000"},
+		Sets: []SetStatus{
+			{ID: "set0", HealStatus: "healing", Disks: []Disk{
+				{State: DriveStateOk, HealInfo: &HealingDisk{ID: "disk1", Endpoint: "server1:9000/d1", ItemsHealed: 10, BytesDone: 100}},
+				{State: DriveStateOk},
+			}},
+			{ID: "set1", Disks: []Disk{{State: DriveStateOk}}},
+		},
+	}
+	cur := BgHealState{
+		OfflineEndpoints: []string{"server4:9000"},
+		Sets: []SetStatus{
+			{ID: "set0", HealStatus: "healing", Disks: []Disk{
+				{State: DriveStateOk, HealInfo: &HealingDisk{ID: "disk1", Endpoint: "server1:9000/d1", ItemsHealed: 12, BytesDone: 250}},
+				{State: DriveStateOffline},
+			}},
+			{ID: "set1", Disks: []Disk{{State: DriveStateOk, HealInfo: &HealingDisk{ID: "disk2", Endpoint: "server2:9000/d1", BytesDone: 10}}}},
+		},
+	}
+
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		state := cur
+		if polls == 0 {
+			state = prev
+		}
+		polls++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(state)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deltaCh, errCh := adm.BackgroundHealDeltas(ctx, time.Millisecond)
+
+	baseline := <-deltaCh
+	if baseline.Baseline == nil || len(baseline.Baseline.Sets) != 2 {
+		t.Fatalf("Expected the baseline first, got %+v", baseline)
+	}
+
+	delta := <-deltaCh
+	cancel()
+	expected := BgHealDelta{
+		Disks: []HealingDiskDelta{
+			{ID: "disk1", Endpoint: "server1:9000/d1", ItemsHealed: 2, BytesDone: 150},
+			{ID: "disk2", Endpoint: "server2:9000/d1", BytesDone: 10, Added: true},
+		},
+		Sets:    []SetStatus{cur.Sets[0]},
+		Offline: []string{"server4:9000"},
+		Online:  []string{"server3:9000"},
+	}
+	if !reflect.DeepEqual(delta, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, delta)
+	}
+
+	for range deltaCh {
+	}
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// Tests a zero poll interval is reported on the error channel.
+func TestBackgroundHealDeltasZeroPoll(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(BgHealState{})
+	})
+
+	deltaCh, errCh := adm.BackgroundHealDeltas(context.Background(), 0)
+	for range deltaCh {
+		t.Error("Expected no delta")
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+}
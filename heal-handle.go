@@ -0,0 +1,70 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"time"
+)
+
+// HealHandle - refers to a heal sequence started by HealAsync and
+// allows to check on it later without keeping track of its client
+// token.
+type HealHandle struct {
+	Bucket    string
+	Prefix    string
+	HealStart HealStartSuccess
+
+	adm  *AdminClient
+	opts HealOpts
+}
+
+// HealAsync - starts a heal sequence on bucket/prefix and returns
+// immediately with a handle to the sequence.
+func (adm *AdminClient) HealAsync(ctx context.Context, bucket, prefix string, opts HealOpts) (*HealHandle, error) {
+	healStart, _, err := adm.Heal(ctx, bucket, prefix, opts, "", false, false)
+	if err != nil {
+		return nil, err
+	}
+	return &HealHandle{
+		Bucket:    bucket,
+		Prefix:    prefix,
+		HealStart: healStart,
+		adm:       adm,
+		opts:      opts,
+	}, nil
+}
+
+// Status - fetches the current status of the heal sequence, see
+// AdminClient.HealStatus.
+func (h *HealHandle) Status(ctx context.Context) (HealTaskStatus, error) {
+	return h.adm.HealStatus(ctx, h.Bucket, h.Prefix, h.HealStart.ClientToken)
+}
+
+// Stop - force stops the heal sequence.
+func (h *HealHandle) Stop(ctx context.Context) error {
+	_, _, err := h.adm.Heal(ctx, h.Bucket, h.Prefix, h.opts, "", false, true)
+	return err
+}
+
+// Wait - polls the heal sequence every poll until it ends, see
+// AdminClient.HealWait. The heal sequence is force stopped when ctx is
+// canceled or expires before it ends. A poll interval which is not
+// positive is rejected with an ErrInvalidArgument error.
+func (h *HealHandle) Wait(ctx context.Context, poll time.Duration, opts ...HealWaitOption) (HealTaskStatus, error) {
+	return h.adm.HealWait(ctx, h.Bucket, h.Prefix, h.HealStart.ClientToken, poll, opts...)
+}
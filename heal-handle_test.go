@@ -0,0 +1,95 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests the methods of a HealHandle against a fake server.
+func TestHealHandle(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		polls   int
+		stopped bool
+	)
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/minio/admin/v3/heal/bucket/prefix" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		query := r.URL.Query()
+		switch {
+		case query.Get("forceStop") == "true":
+			stopped = true
+			json.NewEncoder(w).Encode(HealStopSuccess{ClientToken: "token"})
+		case query.Get("clientToken") == "token":
+			polls++
+			status := HealTaskStatus{Summary: string(HealRunningState), Items: []HealResultItem{{ResultIndex: int64(polls)}}}
+			if polls >= 3 {
+				status.Summary = string(HealFinishedState)
+			}
+			json.NewEncoder(w).Encode(status)
+		default:
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+		}
+	})
+
+	ctx := context.Background()
+	h, err := adm.HealAsync(ctx, "bucket", "prefix", HealOpts{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.HealStart.ClientToken != "token" {
+		t.Errorf("Expected token, got %q", h.HealStart.ClientToken)
+	}
+
+	status, err := h.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State() != HealRunningState {
+		t.Errorf("Expected %q, got %q", HealRunningState, status.State())
+	}
+
+	if _, err = h.Wait(ctx, 0); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+
+	status, err = h.Wait(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State() != HealFinishedState || len(status.Items) != 2 {
+		t.Errorf("Unexpected final status %+v", status)
+	}
+
+	if err = h.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !stopped {
+		t.Error("Expected the heal to be force stopped")
+	}
+}
@@ -0,0 +1,97 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxTagEscaper escapes tag values as required by the InfluxDB line
+// protocol, newlines which can't be escaped are replaced by a space.
+var influxTagEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `)
+
+// influxStringEscaper escapes string field values as required by the
+// InfluxDB line protocol.
+var influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// influxPoint writes a point to buf, tags are given as key and value
+// pairs and fields as already formatted key=value strings.
+func influxPoint(buf *bytes.Buffer, measurement string, ts time.Time, tags []string, fields ...string) {
+	buf.WriteString(measurement)
+	for i := 0; i+1 < len(tags); i += 2 {
+		buf.WriteByte(',')
+		buf.WriteString(tags[i])
+		buf.WriteByte('=')
+		buf.WriteString(influxTagEscaper.Replace(tags[i+1]))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strings.Join(fields, ","))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(ts.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+// influxInt formats a signed 64-bit integer field, the only integer
+// type supported by all the versions of InfluxDB.
+func influxInt(key string, value int64) string {
+	return key + "=" + strconv.FormatInt(value, 10) + "i"
+}
+
+// WriteInfluxLineProtocol writes the background heal state to w in the
+// InfluxDB line protocol, all the points with the timestamp ts: the
+// scanned items and state of background healing (minio_heal), the MRF
+// progress of each endpoint (minio_heal_mrf) and the drive health of
+// each set (minio_heal_set).
+func (b BgHealState) WriteInfluxLineProtocol(w io.Writer, ts time.Time) error {
+	var buf bytes.Buffer
+
+	influxPoint(&buf, "minio_heal", ts, nil,
+		influxInt("scanned_items", b.ScannedItemsCount),
+		"paused="+strconv.FormatBool(b.Paused),
+		influxInt("offline_endpoints", int64(len(b.OfflineEndpoints))))
+
+	endpoints := make([]string, 0, len(b.MRF))
+	for endpoint := range b.MRF {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		mrf := b.MRF[endpoint]
+		influxPoint(&buf, "minio_heal_mrf", ts, []string{"endpoint", endpoint},
+			influxInt("items_healed", int64(mrf.ItemsHealed)),
+			influxInt("bytes_healed", int64(mrf.BytesHealed)),
+			influxInt("total_items", int64(mrf.TotalItems)),
+			influxInt("total_bytes", int64(mrf.TotalBytes)))
+	}
+
+	for _, set := range b.Sets {
+		online, total := set.DriveHealth()
+		influxPoint(&buf, "minio_heal_set", ts,
+			[]string{"pool", strconv.Itoa(set.PoolIndex), "set", strconv.Itoa(set.SetIndex)},
+			influxInt("drives_online", int64(online)),
+			influxInt("drives_total", int64(total)),
+			`heal_status="`+influxStringEscaper.Replace(set.HealStatus)+`"`)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,70 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// influxLine matches a line protocol point with integer, boolean or
+// string fields.
+var influxLine = regexp.MustCompile(`^[a-z_]+` +
+	`(,[a-z_]+=(?:[^\\, =\n]|\\.)+)*` +
+	` [a-z_]+=(?:-?[0-9]+i|true|false|"(?:[^"\\\n]|\\.)*")(,[a-z_]+=(?:-?[0-9]+i|true|false|"(?:[^"\\\n]|\\.)*"))*` +
+	` [0-9]+$`)
+
+// Tests WriteInfluxLineProtocol writes well-formed line protocol.
+func TestBgHealStateWriteInfluxLineProtocol(t *testing.T) {
+	state := BgHealState{
+		ScannedItemsCount: 1000,
+		OfflineEndpoints:  []string{"server3:9000"},
+		MRF: map[string]MRFStatus{
+			"server1:9000":           {ItemsHealed: 5, BytesHealed: 1 << 30, TotalItems: 10},
+			"server 2,a=b\\:9000\nx": {ItemsHealed: 1},
+		},
+		Sets: []SetStatus{
+			{PoolIndex: 0, SetIndex: 1, HealStatus: `say "hi"`, Disks: []Disk{{State: DriveStateOk}, {State: DriveStateOffline}}},
+		},
+	}
+	ts := time.Unix(1625133600, 0)
+
+	var buf bytes.Buffer
+	if err := state.WriteInfluxLineProtocol(&buf, ts); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for i, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !influxLine.MatchString(line) {
+			t.Errorf("Line %d is not valid line protocol: %q", i+1, line)
+		}
+	}
+
+	for _, line := range []string{
+		"minio_heal scanned_items=1000i,paused=false,offline_endpoints=1i 1625133600000000000\n",
+		"minio_heal_mrf,endpoint=server1:9000 items_healed=5i,bytes_healed=1073741824i,total_items=10i,total_bytes=0i 1625133600000000000\n",
+		`minio_heal_mrf,endpoint=server\ 2\,a\=b\\:9000\ x items_healed=1i`,
+		`minio_heal_set,pool=0,set=1 drives_online=1i,drives_total=2i,heal_status="say \"hi\"" 1625133600000000000` + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// promLabelEscaper escapes label values as required by the Prometheus
+// text exposition format.
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promMetric writes the HELP and TYPE lines of a gauge to buf.
+func promMetric(buf *bytes.Buffer, name, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// promSample writes a sample of a gauge to buf, labels are given as
+// name and value pairs.
+func promSample(buf *bytes.Buffer, name string, value float64, labels ...string) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, `%s="%s"`, labels[i], promLabelEscaper.Replace(labels[i+1]))
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+// WritePrometheus writes the background heal state to w as gauges in
+// the Prometheus text exposition format: the scanned items, the state
+// of background healing, the MRF progress of each endpoint and the
+// drive health of each set.
+func (b BgHealState) WritePrometheus(w io.Writer) error {
+	var buf bytes.Buffer
+
+	promMetric(&buf, "minio_heal_scanned_items", "Items scanned by background healing.")
+	promSample(&buf, "minio_heal_scanned_items", float64(b.ScannedItemsCount))
+
+	paused := 0.0
+	if b.Paused {
+		paused = 1
+	}
+	promMetric(&buf, "minio_heal_paused", "Whether background healing is paused.")
+	promSample(&buf, "minio_heal_paused", paused)
+
+	promMetric(&buf, "minio_heal_offline_endpoints", "Endpoints without background heal information.")
+	promSample(&buf, "minio_heal_offline_endpoints", float64(len(b.OfflineEndpoints)))
+
+	endpoints := make([]string, 0, len(b.MRF))
+	for endpoint := range b.MRF {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	mrfMetrics := []struct {
+		name, help string
+		value      func(MRFStatus) uint64
+	}{
+		{"minio_heal_mrf_items_healed", "Items healed by MRF per endpoint.", func(m MRFStatus) uint64 { return m.ItemsHealed }},
+		{"minio_heal_mrf_bytes_healed", "Bytes healed by MRF per endpoint.", func(m MRFStatus) uint64 { return m.BytesHealed }},
+		{"minio_heal_mrf_total_items", "Items queued for MRF per endpoint.", func(m MRFStatus) uint64 { return m.TotalItems }},
+	}
+	for _, m := range mrfMetrics {
+		promMetric(&buf, m.name, m.help)
+		for _, endpoint := range endpoints {
+			promSample(&buf, m.name, float64(m.value(b.MRF[endpoint])), "endpoint", endpoint)
+		}
+	}
+
+	promMetric(&buf, "minio_heal_set_drives_online", "Online drives per erasure set.")
+	for _, set := range b.Sets {
+		online, _ := set.DriveHealth()
+		promSample(&buf, "minio_heal_set_drives_online", float64(online),
+			"pool", strconv.Itoa(set.PoolIndex), "set", strconv.Itoa(set.SetIndex))
+	}
+	promMetric(&buf, "minio_heal_set_drives_total", "Drives per erasure set.")
+	for _, set := range b.Sets {
+		_, total := set.DriveHealth()
+		promSample(&buf, "minio_heal_set_drives_total", float64(total),
+			"pool", strconv.Itoa(set.PoolIndex), "set", strconv.Itoa(set.SetIndex))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,70 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Prometheus text exposition format lines.
+var (
+	promCommentLine = regexp.MustCompile(`^# (HELP|TYPE) [a-zA-Z_:][a-zA-Z0-9_:]* .+$`)
+	promSampleLine  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*` +
+		`(\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*")*\})?` +
+		` [-+]?[0-9.eE+-]+$`)
+)
+
+// Tests WritePrometheus writes valid exposition text.
+func TestBgHealStateWritePrometheus(t *testing.T) {
+	state := BgHealState{
+		ScannedItemsCount: 1000,
+		OfflineEndpoints:  []string{"server3:9000"},
+		MRF: map[string]MRFStatus{
+			"server1:9000":          {ItemsHealed: 5, BytesHealed: 1 << 30, TotalItems: 10},
+			"server\"2\"\\:9000\nx": {ItemsHealed: 1},
+		},
+		Sets: []SetStatus{
+			{PoolIndex: 0, SetIndex: 1, Disks: []Disk{{State: DriveStateOk}, {State: DriveStateOffline}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := state.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for i, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !promCommentLine.MatchString(line) && !promSampleLine.MatchString(line) {
+			t.Errorf("Line %d is not valid exposition text: %q", i+1, line)
+		}
+	}
+
+	for _, sample := range []string{
+		"minio_heal_scanned_items 1000\n",
+		`minio_heal_mrf_bytes_healed{endpoint="server1:9000"} 1.073741824e+09` + "\n",
+		`minio_heal_mrf_items_healed{endpoint="server\"2\"\\:9000\nx"} 1` + "\n",
+		`minio_heal_set_drives_online{pool="0",set="1"} 1` + "\n",
+		`minio_heal_set_drives_total{pool="0",set="1"} 2` + "\n",
+	} {
+		if !strings.Contains(out, sample) {
+			t.Errorf("Expected sample %q in\n%s", sample, out)
+		}
+	}
+}
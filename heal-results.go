@@ -0,0 +1,571 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Key - returns a key identifying the healed item, made of its
+// type, bucket, object and version.
+func (hri HealResultItem) Key() string {
+	return string(hri.Type) + ":" + path.Join(hri.Bucket, hri.Object) + "?" + hri.VersionID
+}
+
+// HealResultDiff - a discrepancy between the items of a dry-run heal
+// and the items of the real heal.
+type HealResultDiff struct {
+	Type      HealItemType `json:"type"`
+	Bucket    string       `json:"bucket"`
+	Object    string       `json:"object"`
+	VersionID string       `json:"versionId"`
+
+	// Planned is true if the item was reported by the dry-run.
+	Planned bool `json:"planned"`
+	// Healed is true if the item was reported by the real heal.
+	Healed bool `json:"healed"`
+}
+
+// DiffHealStatus - compares the items reported by a dry-run heal with
+// the items reported by the real heal, pairing them by Key. Items
+// planned but not healed and items healed but not planned are
+// returned, in the order they were reported.
+func DiffHealStatus(dry, real HealTaskStatus) []HealResultDiff {
+	planned := make(map[string]struct{}, len(dry.Items))
+	for _, item := range dry.Items {
+		planned[item.Key()] = struct{}{}
+	}
+	healed := make(map[string]struct{}, len(real.Items))
+	for _, item := range real.Items {
+		healed[item.Key()] = struct{}{}
+	}
+
+	var diffs []HealResultDiff
+	newDiff := func(item HealResultItem) HealResultDiff {
+		return HealResultDiff{
+			Type:      item.Type,
+			Bucket:    item.Bucket,
+			Object:    item.Object,
+			VersionID: item.VersionID,
+		}
+	}
+	for _, item := range dry.Items {
+		if _, ok := healed[item.Key()]; !ok {
+			diff := newDiff(item)
+			diff.Planned = true
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, item := range real.Items {
+		if _, ok := planned[item.Key()]; !ok {
+			diff := newDiff(item)
+			diff.Healed = true
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// RequiredRebuild returns true if healing the object required shards
+// to be reconstructed from parity, i.e. at least one drive which was
+// corrupt or missing before the heal is ok after it. This is a
+// heuristic, metadata only heals and heals of non object items are
+// never reported as a rebuild.
+func (hri HealResultItem) RequiredRebuild() bool {
+	if hri.Type != HealItemObject {
+		return false
+	}
+	for i, before := range hri.Before.Drives {
+		if before.State != DriveStateCorrupt && before.State != DriveStateMissing {
+			continue
+		}
+		if i < len(hri.After.Drives) && hri.After.Drives[i].State == DriveStateOk {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsAttention returns true if the drive is in a state which needs
+// to be healed or fixed by an operator: corrupt, missing, faulty or
+// permission denied. Offline drives are considered transient and,
+// like drives in an ok or unknown state, don't need attention.
+func (d HealDriveInfo) NeedsAttention() bool {
+	switch d.State {
+	case DriveStateCorrupt, DriveStateMissing, DriveStateFaulty, DriveStatePermission:
+		return true
+	}
+	return false
+}
+
+// Healthy returns true if the drive neither needs attention nor is
+// offline, see NeedsAttention. Drives in an unknown or unformatted
+// state are considered healthy.
+func (d HealDriveInfo) Healthy() bool {
+	return !d.NeedsAttention() && d.State != DriveStateOffline
+}
+
+// FaultReason returns the last error reported by the server for the
+// drive, e.g. why it is faulty. It is empty when the drive had no error
+// or the server does not report drive errors.
+func (d HealDriveInfo) FaultReason() string {
+	return d.Error
+}
+
+// PermissionDeniedDrives returns the drives of the object which were
+// in the DriveStatePermission state before or after the heal. Unlike
+// corrupt drives they are fixed by correcting the ownership or mode of
+// the drive, not by healing.
+func (hri HealResultItem) PermissionDeniedDrives() []HealDriveInfo {
+	var drives []HealDriveInfo
+	seen := make(map[string]bool)
+	for _, side := range [][]HealDriveInfo{hri.Before.Drives, hri.After.Drives} {
+		for _, d := range side {
+			if d.State == DriveStatePermission && !seen[d.Endpoint] {
+				seen[d.Endpoint] = true
+				drives = append(drives, d)
+			}
+		}
+	}
+	return drives
+}
+
+// ShardsToRead estimates the number of shards read to heal the object.
+// Rebuilding a shard requires reading DataBlocks shards, bounded by the
+// number of drives which were online before the heal. Zero is
+// returned when no rebuild was required, see RequiredRebuild.
+func (hri HealResultItem) ShardsToRead() int {
+	if !hri.RequiredRebuild() {
+		return 0
+	}
+	online, _ := hri.GetOnlineCounts()
+	if hri.DataBlocks < online {
+		return hri.DataBlocks
+	}
+	return online
+}
+
+// DriveHealSummary - heal activity of a single drive, see GroupByDrive.
+type DriveHealSummary struct {
+	// Items is the number of heal result items involving the drive.
+	Items int `json:"items"`
+	// Healed is the number of items the drive transitioned to ok for.
+	Healed int `json:"healed"`
+}
+
+// GroupByDrive summarizes the heal activity of each drive, keyed by
+// drive endpoint. Drives are read from the After drives of each item,
+// an item counts as healed on a drive if the drive was not ok before
+// the heal and is ok after it.
+func GroupByDrive(items []HealResultItem) map[string]DriveHealSummary {
+	summaries := make(map[string]DriveHealSummary)
+	for _, item := range items {
+		before := make(map[string]string, len(item.Before.Drives))
+		for _, d := range item.Before.Drives {
+			before[d.Endpoint] = d.State
+		}
+		for _, d := range item.After.Drives {
+			s := summaries[d.Endpoint]
+			s.Items++
+			if d.State == DriveStateOk && before[d.Endpoint] != DriveStateOk {
+				s.Healed++
+			}
+			summaries[d.Endpoint] = s
+		}
+	}
+	return summaries
+}
+
+// HealSummaryMaxFailures is the maximum number of failed items kept
+// in a HealTaskSummary.
+const HealSummaryMaxFailures = 10
+
+// HealFailure - an item which could not be healed, see HealTaskSummary.
+type HealFailure struct {
+	ResultIndex int64        `json:"resultId"`
+	Type        HealItemType `json:"type"`
+	Bucket      string       `json:"bucket"`
+	Object      string       `json:"object,omitempty"`
+	VersionID   string       `json:"versionId,omitempty"`
+	Detail      string       `json:"detail,omitempty"`
+}
+
+// HealTaskSummary - compact form of a HealTaskStatus, without the
+// result items, see HealTaskStatus.Compact.
+type HealTaskSummary struct {
+	Summary       string        `json:"summary"`
+	FailureDetail string        `json:"detail,omitempty"`
+	StartTime     time.Time     `json:"startTime"`
+	Elapsed       time.Duration `json:"elapsed"`
+	HealSettings  HealOpts      `json:"settings"`
+
+	Items    int `json:"items"`
+	Healed   int `json:"healed"`
+	Failed   int `json:"failed"`
+	DataLoss int `json:"dataLoss"`
+
+	// Failures holds the first HealSummaryMaxFailures items which
+	// failed or lost data.
+	Failures []HealFailure `json:"failures,omitempty"`
+}
+
+// Compact summarizes the status, dropping its items. Each item is
+// counted once: as a data loss, see DataLoss, as failed if a drive
+// still needs attention after the heal, and as healed if a drive went
+// from not ok to ok. Elapsed runs from the start of the heal to
+// lastUpdate, e.g. adm.Now() or the time the heal was seen ending.
+func (s HealTaskStatus) Compact(lastUpdate time.Time) HealTaskSummary {
+	summary := HealTaskSummary{
+		Summary:       s.Summary,
+		FailureDetail: s.FailureDetail,
+		StartTime:     s.StartTime,
+		HealSettings:  s.HealSettings,
+		Items:         len(s.Items),
+	}
+	if !s.StartTime.IsZero() && lastUpdate.After(s.StartTime) {
+		summary.Elapsed = lastUpdate.Sub(s.StartTime)
+	}
+	for _, item := range s.Items {
+		failed := false
+		switch {
+		case item.DataLoss():
+			summary.DataLoss++
+			failed = true
+		case item.needsAttentionAfter():
+			summary.Failed++
+			failed = true
+		case item.healedDrive():
+			summary.Healed++
+		}
+		if failed && len(summary.Failures) < HealSummaryMaxFailures {
+			summary.Failures = append(summary.Failures, HealFailure{
+				ResultIndex: item.ResultIndex,
+				Type:        item.Type,
+				Bucket:      item.Bucket,
+				Object:      item.Object,
+				VersionID:   item.VersionID,
+				Detail:      item.Detail,
+			})
+		}
+	}
+	return summary
+}
+
+// DataLoss returns true if the object has fewer online drives than
+// data blocks after the heal, i.e. it can't be read anymore.
+func (hri HealResultItem) DataLoss() bool {
+	if hri.Type != HealItemObject || hri.DataBlocks <= 0 {
+		return false
+	}
+	_, online := hri.GetOnlineCounts()
+	return online < hri.DataBlocks
+}
+
+// SpareParity returns the number of drives the object can still lose
+// after the heal without losing data, i.e. its online drives in excess
+// of its data blocks. It is negative for objects which lost data and
+// -1 for items without data blocks.
+func (hri HealResultItem) SpareParity() int {
+	if hri.Type != HealItemObject || hri.DataBlocks <= 0 {
+		return -1
+	}
+	_, online := hri.GetOnlineCounts()
+	return online - hri.DataBlocks
+}
+
+// FilterByParityLoss returns the objects of items whose spare parity
+// after the heal is at or below maxSpare, see HealOpts.MinParityLoss.
+// Items without data blocks are skipped.
+func FilterByParityLoss(items []HealResultItem, maxSpare int) []HealResultItem {
+	var filtered []HealResultItem
+	for _, item := range items {
+		if item.Type != HealItemObject || item.DataBlocks <= 0 {
+			continue
+		}
+		if item.SpareParity() <= maxSpare {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// HadDataLoss returns true if any item of the status lost data, see
+// HealResultItem.DataLoss.
+func (s HealTaskStatus) HadDataLoss() bool {
+	for _, item := range s.Items {
+		if item.DataLoss() {
+			return true
+		}
+	}
+	return false
+}
+
+// DataLossObjects returns the items of the status which lost data.
+func (s HealTaskStatus) DataLossObjects() []HealResultItem {
+	var items []HealResultItem
+	for _, item := range s.Items {
+		if item.DataLoss() {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// needsAttentionAfter returns true if a drive needs attention after
+// the heal.
+func (hri HealResultItem) needsAttentionAfter() bool {
+	for _, d := range hri.After.Drives {
+		if d.NeedsAttention() {
+			return true
+		}
+	}
+	return false
+}
+
+// healedDrive returns true if a drive went from not ok to ok.
+func (hri HealResultItem) healedDrive() bool {
+	for i, before := range hri.Before.Drives {
+		if before.State != DriveStateOk && i < len(hri.After.Drives) && hri.After.Drives[i].State == DriveStateOk {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownStorageClass is the storage class under which
+// CoverageByStorageClass groups the items without a storage class.
+const UnknownStorageClass = "UNKNOWN"
+
+// Coverage - number of objects by redundancy after a heal, see
+// CoverageByStorageClass.
+type Coverage struct {
+	// Full counts the objects with all their drives healthy.
+	Full int `json:"full"`
+	// Degraded counts the readable objects with drives which are
+	// not healthy.
+	Degraded int `json:"degraded"`
+	// Lost counts the objects which lost data, see
+	// HealResultItem.DataLoss.
+	Lost int `json:"lost"`
+}
+
+// CoverageByStorageClass counts the objects of items by redundancy
+// after the heal, grouped by storage class. Objects without a storage
+// class are grouped under UnknownStorageClass, items which are not
+// objects are skipped.
+func CoverageByStorageClass(items []HealResultItem) map[string]Coverage {
+	coverage := make(map[string]Coverage)
+	for _, item := range items {
+		if item.Type != HealItemObject {
+			continue
+		}
+		class := item.StorageClass
+		if class == "" {
+			class = UnknownStorageClass
+		}
+		c := coverage[class]
+		switch {
+		case item.DataLoss():
+			c.Lost++
+		case item.fullyRedundant():
+			c.Full++
+		default:
+			c.Degraded++
+		}
+		coverage[class] = c
+	}
+	return coverage
+}
+
+// fullyRedundant returns true if all the drives are healthy after the
+// heal.
+func (hri HealResultItem) fullyRedundant() bool {
+	for _, d := range hri.After.Drives {
+		if !d.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// HealFailureReason - machine readable reason of a heal failure,
+// see HealResultItem.FailureReason.
+type HealFailureReason int
+
+// HealFailureReason constants
+const (
+	// HealFailureNone is returned when the item has no detail.
+	HealFailureNone HealFailureReason = iota
+	// HealFailureUnknown is returned for details not recognized, e.g.
+	// messages introduced by newer servers.
+	HealFailureUnknown
+	HealFailureInsufficientDrives
+	HealFailureBitrotDetected
+	HealFailurePermissionDenied
+)
+
+// String returns the name of the reason.
+func (r HealFailureReason) String() string {
+	switch r {
+	case HealFailureNone:
+		return "None"
+	case HealFailureInsufficientDrives:
+		return "InsufficientDrives"
+	case HealFailureBitrotDetected:
+		return "BitrotDetected"
+	case HealFailurePermissionDenied:
+		return "PermissionDenied"
+	}
+	return "Unknown"
+}
+
+// healFailureMessages maps lower cased fragments of the messages of
+// known server errors to their reason.
+var healFailureMessages = []struct {
+	fragment string
+	reason   HealFailureReason
+}{
+	{"insufficient number of drives", HealFailureInsufficientDrives},
+	{"insufficient number of disks", HealFailureInsufficientDrives},
+	{"quorum", HealFailureInsufficientDrives},
+	{"bitrot", HealFailureBitrotDetected},
+	{"file is corrupted", HealFailureBitrotDetected},
+	{"access denied", HealFailurePermissionDenied},
+	{"permission denied", HealFailurePermissionDenied},
+}
+
+// FailureReason parses the detail of the item, on a best effort basis,
+// into a reason and returns it with the raw detail.
+// HealFailureUnknown is returned for unrecognized details.
+func (hri HealResultItem) FailureReason() (HealFailureReason, string) {
+	if hri.Detail == "" {
+		return HealFailureNone, ""
+	}
+	detail := strings.ToLower(hri.Detail)
+	for _, m := range healFailureMessages {
+		if strings.Contains(detail, m.fragment) {
+			return m.reason, hri.Detail
+		}
+	}
+	return HealFailureUnknown, hri.Detail
+}
+
+// HealLoopDetector - counts how many times objects are healed across
+// observations of heal results, e.g. of successive background heal
+// cycles, to detect objects which keep needing to be healed. It is
+// safe for concurrent use, its zero value is ready to use.
+type HealLoopDetector struct {
+	mu     sync.Mutex
+	healed map[string]int
+}
+
+// Observe counts the objects healed in items, i.e. with a drive which
+// went from not ok to ok. An object is counted once per observation.
+func (d *HealLoopDetector) Observe(items []HealResultItem) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.healed == nil {
+		d.healed = make(map[string]int)
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.Type != HealItemObject || !item.healedDrive() {
+			continue
+		}
+		key := item.Key()
+		if !seen[key] {
+			seen[key] = true
+			d.healed[key]++
+		}
+	}
+}
+
+// Looping returns the keys, see HealResultItem.Key, of the objects
+// healed in at least minRepeats observations, sorted.
+func (d *HealLoopDetector) Looping(minRepeats int) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var keys []string
+	for key, n := range d.healed {
+		if n >= minRepeats {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ProcessItems calls fn for each of the items from workers concurrent
+// goroutines, in no particular order. Workers share the items, which
+// are not copied. The first error returned by fn cancels the context
+// passed to the other calls, no more items are processed and the error
+// is returned. The error of ctx is returned if it is canceled before
+// all the items are processed.
+func ProcessItems(ctx context.Context, items []HealResultItem, workers int, fn func(context.Context, HealResultItem) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		once      sync.Once
+		firstErr  error
+		next      int64 = -1
+		processed int64
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(len(items)) {
+					return
+				}
+				if err := fn(ctx, items[i]); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				atomic.AddInt64(&processed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if processed < int64(len(items)) {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests DiffHealStatus with a real heal missing a planned item.
+func TestDiffHealStatus(t *testing.T) {
+	dry := HealTaskStatus{
+		HealSettings: HealOpts{DryRun: true},
+		Items: []HealResultItem{
+			{Type: HealItemBucket, Bucket: "bucket"},
+			{Type: HealItemObject, Bucket: "bucket", Object: "a"},
+			{Type: HealItemObject, Bucket: "bucket", Object: "b", VersionID: "v1"},
+		},
+	}
+	real := HealTaskStatus{
+		Items: []HealResultItem{
+			{Type: HealItemBucket, Bucket: "bucket"},
+			{Type: HealItemObject, Bucket: "bucket", Object: "a"},
+		},
+	}
+
+	expected := []HealResultDiff{
+		{Type: HealItemObject, Bucket: "bucket", Object: "b", VersionID: "v1", Planned: true},
+	}
+	if diffs := DiffHealStatus(dry, real); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diffs)
+	}
+	if diffs := DiffHealStatus(dry, dry); len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %+v", diffs)
+	}
+}
+
+// Tests RequiredRebuild for metadata and shard heals.
+func TestRequiredRebuild(t *testing.T) {
+	drives := func(states ...string) []HealDriveInfo {
+		var infos []HealDriveInfo
+		for _, state := range states {
+			infos = append(infos, HealDriveInfo{State: state})
+		}
+		return infos
+	}
+
+	metadata := HealResultItem{Type: HealItemMetadata}
+	metadata.Before.Drives = drives(DriveStateOk, DriveStateMissing)
+	metadata.After.Drives = drives(DriveStateOk, DriveStateOk)
+	if metadata.RequiredRebuild() {
+		t.Error("Expected metadata heal to not require rebuild")
+	}
+
+	unchanged := HealResultItem{Type: HealItemObject}
+	unchanged.Before.Drives = drives(DriveStateOk, DriveStateOffline)
+	unchanged.After.Drives = drives(DriveStateOk, DriveStateOffline)
+	if unchanged.RequiredRebuild() {
+		t.Error("Expected object with offline drive to not require rebuild")
+	}
+
+	rebuilt := HealResultItem{Type: HealItemObject}
+	rebuilt.Before.Drives = drives(DriveStateOk, DriveStateCorrupt, DriveStateOk)
+	rebuilt.After.Drives = drives(DriveStateOk, DriveStateOk, DriveStateOk)
+	if !rebuilt.RequiredRebuild() {
+		t.Error("Expected object with corrupt shard to require rebuild")
+	}
+}
+
+// Tests drive classification for each drive state.
+func TestHealDriveInfoClassification(t *testing.T) {
+	testCases := []struct {
+		state          string
+		needsAttention bool
+		healthy        bool
+	}{
+		{DriveStateOk, false, true},
+		{DriveStateOffline, false, false},
+		{DriveStateCorrupt, true, false},
+		{DriveStateMissing, true, false},
+		{DriveStatePermission, true, false},
+		{DriveStateFaulty, true, false},
+		{DriveStateUnknown, false, true},
+		{DriveStateUnformatted, false, true},
+	}
+	for _, testCase := range testCases {
+		d := HealDriveInfo{State: testCase.state}
+		if d.NeedsAttention() != testCase.needsAttention {
+			t.Errorf("%s: expected NeedsAttention %v", testCase.state, testCase.needsAttention)
+		}
+		if d.Healthy() != testCase.healthy {
+			t.Errorf("%s: expected Healthy %v", testCase.state, testCase.healthy)
+		}
+	}
+}
+
+// Tests ShardsToRead for rebuilt and healthy objects.
+func TestShardsToRead(t *testing.T) {
+	item := HealResultItem{Type: HealItemObject, DataBlocks: 2, ParityBlocks: 2}
+	item.Before.Drives = []HealDriveInfo{{State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateMissing}}
+	item.After.Drives = []HealDriveInfo{{State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOk}}
+	if n := item.ShardsToRead(); n != 2 {
+		t.Errorf("Expected 2 shards, got %d", n)
+	}
+
+	item.DataBlocks = 4
+	if n := item.ShardsToRead(); n != 3 {
+		t.Errorf("Expected shards bounded by 3 online drives, got %d", n)
+	}
+
+	item.Before.Drives = item.After.Drives
+	if n := item.ShardsToRead(); n != 0 {
+		t.Errorf("Expected no shards for healthy object, got %d", n)
+	}
+}
+
+// Tests GroupByDrive over items spanning two drives.
+func TestGroupByDrive(t *testing.T) {
+	newItem := func(before, after [2]string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject}
+		for i, endpoint := range []string{"http://server1/disk1", "http://server2/disk1"} {
+			item.Before.Drives = append(item.Before.Drives, HealDriveInfo{Endpoint: endpoint, State: before[i]})
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{Endpoint: endpoint, State: after[i]})
+		}
+		return item
+	}
+	items := []HealResultItem{
+		newItem([2]string{DriveStateOk, DriveStateMissing}, [2]string{DriveStateOk, DriveStateOk}),
+		newItem([2]string{DriveStateCorrupt, DriveStateMissing}, [2]string{DriveStateOk, DriveStateOk}),
+		newItem([2]string{DriveStateOk, DriveStateOffline}, [2]string{DriveStateOk, DriveStateOffline}),
+	}
+
+	expected := map[string]DriveHealSummary{
+		"http://server1/disk1": {Items: 3, Healed: 1},
+		"http://server2/disk1": {Items: 3, Healed: 2},
+	}
+	if summaries := GroupByDrive(items); !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("Expected %v, got %v", expected, summaries)
+	}
+}
+
+// Tests the drive error is decoded when present and omitted otherwise.
+func TestHealDriveInfoFaultReason(t *testing.T) {
+	testCases := []struct {
+		data   string
+		reason string
+	}{
+		{`{"uuid":"u1","endpoint":"http://server1/disk1","state":"faulty","error":"drive is not writable"}`, "drive is not writable"},
+		// Older servers don't report drive errors.
+		{`{"uuid":"u1","endpoint":"http://server1/disk1","state":"faulty"}`, ""},
+	}
+	for i, testCase := range testCases {
+		var d HealDriveInfo
+		if err := json.Unmarshal([]byte(testCase.data), &d); err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		if d.FaultReason() != testCase.reason {
+			t.Errorf("Test %d: Expected reason %q, got %q", i+1, testCase.reason, d.FaultReason())
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		if string(data) != testCase.data {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.data, data)
+		}
+	}
+}
+
+// Tests Compact drops the items but keeps their counts.
+func TestHealTaskStatusCompact(t *testing.T) {
+	newItem := func(index int64, dataBlocks int, before, after [2]string) HealResultItem {
+		item := HealResultItem{ResultIndex: index, Type: HealItemObject, Bucket: "bucket", Object: "object", DataBlocks: dataBlocks}
+		for i := range before {
+			item.Before.Drives = append(item.Before.Drives, HealDriveInfo{State: before[i]})
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: after[i]})
+		}
+		return item
+	}
+	status := HealTaskStatus{
+		Summary:      string(HealFinishedState),
+		StartTime:    time.Unix(1000, 0),
+		HealSettings: HealOpts{Recursive: true},
+		Items: []HealResultItem{
+			{ResultIndex: 1, Type: HealItemBucket, Bucket: "bucket"},
+			newItem(2, 1, [2]string{DriveStateOk, DriveStateMissing}, [2]string{DriveStateOk, DriveStateOk}),
+			newItem(3, 1, [2]string{DriveStateOk, DriveStateCorrupt}, [2]string{DriveStateOk, DriveStateCorrupt}),
+			newItem(4, 2, [2]string{DriveStateMissing, DriveStateMissing}, [2]string{DriveStateMissing, DriveStateOk}),
+		},
+	}
+
+	summary := status.Compact(time.Unix(1060, 0))
+	if summary.Items != 4 || summary.Healed != 1 || summary.Failed != 1 || summary.DataLoss != 1 {
+		t.Errorf("Unexpected counts %+v", summary)
+	}
+	if len(summary.Failures) != 2 || summary.Failures[0].ResultIndex != 3 || summary.Failures[1].ResultIndex != 4 {
+		t.Errorf("Unexpected failures %+v", summary.Failures)
+	}
+	if summary.Elapsed != time.Minute || !summary.HealSettings.Recursive {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	if summary = status.Compact(time.Unix(900, 0)); summary.Elapsed != 0 {
+		t.Errorf("Expected no elapsed time before the start, got %v", summary.Elapsed)
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"items":[`) {
+		t.Errorf("Expected summary to omit items, got %s", data)
+	}
+}
+
+// Tests FailureReason maps representative server messages.
+func TestHealResultItemFailureReason(t *testing.T) {
+	testCases := []struct {
+		detail string
+		reason HealFailureReason
+	}{
+		{"", HealFailureNone},
+		{"Read failed. Insufficient number of drives online", HealFailureInsufficientDrives},
+		{"Write failed. Insufficient number of disks online", HealFailureInsufficientDrives},
+		{"bitrot hash algorithm is invalid", HealFailureBitrotDetected},
+		{"file is corrupted", HealFailureBitrotDetected},
+		{"drive access denied", HealFailurePermissionDenied},
+		{"open /data1/bucket/object/xl.meta: permission denied", HealFailurePermissionDenied},
+		{"some error introduced later", HealFailureUnknown},
+	}
+	for i, testCase := range testCases {
+		reason, detail := HealResultItem{Detail: testCase.detail}.FailureReason()
+		if reason != testCase.reason || detail != testCase.detail {
+			t.Errorf("Test %d: Expected %v, got %v (%q)", i+1, testCase.reason, reason, detail)
+		}
+	}
+}
+
+// Tests HadDataLoss and DataLossObjects with one unreadable object.
+func TestHealTaskStatusDataLoss(t *testing.T) {
+	newItem := func(object string, after ...string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: object, DataBlocks: 2, ParityBlocks: 1}
+		for _, state := range after {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: state})
+		}
+		return item
+	}
+	status := HealTaskStatus{Items: []HealResultItem{
+		{Type: HealItemBucket, Bucket: "bucket"},
+		newItem("readable", DriveStateOk, DriveStateOk, DriveStateMissing),
+		newItem("lost", DriveStateOk, DriveStateCorrupt, DriveStateMissing),
+	}}
+
+	if !status.HadDataLoss() {
+		t.Error("Expected data loss")
+	}
+	if items := status.DataLossObjects(); len(items) != 1 || items[0].Object != "lost" {
+		t.Errorf("Unexpected data loss items %+v", items)
+	}
+
+	status.Items = status.Items[:2]
+	if status.HadDataLoss() || len(status.DataLossObjects()) != 0 {
+		t.Error("Expected no data loss")
+	}
+}
+
+// Tests HealLoopDetector flags an object healed in every observation.
+func TestHealLoopDetector(t *testing.T) {
+	healed := func(object string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: object}
+		item.Before.Drives = []HealDriveInfo{{State: DriveStateOk}, {State: DriveStateMissing}}
+		item.After.Drives = []HealDriveInfo{{State: DriveStateOk}, {State: DriveStateOk}}
+		return item
+	}
+	healthy := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: "healthy"}
+	healthy.Before.Drives = []HealDriveInfo{{State: DriveStateOk}}
+	healthy.After.Drives = []HealDriveInfo{{State: DriveStateOk}}
+
+	var d HealLoopDetector
+	d.Observe([]HealResultItem{healed("sick"), healed("once"), healthy})
+	d.Observe([]HealResultItem{healed("sick"), healed("sick"), healthy})
+	d.Observe([]HealResultItem{healed("sick"), healthy})
+
+	sick := healed("sick").Key()
+	if keys := d.Looping(3); !reflect.DeepEqual(keys, []string{sick}) {
+		t.Errorf("Expected %v, got %v", []string{sick}, keys)
+	}
+	if keys := d.Looping(1); len(keys) != 2 {
+		t.Errorf("Expected 2 healed objects, got %v", keys)
+	}
+}
+
+// Tests CoverageByStorageClass with objects across two storage classes
+// and without a storage class.
+func TestCoverageByStorageClass(t *testing.T) {
+	object := func(class string, states ...string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject, StorageClass: class, DataBlocks: 2}
+		for _, state := range states {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: state})
+		}
+		return item
+	}
+	items := []HealResultItem{
+		{Type: HealItemBucket, Bucket: "bucket"},
+		object("STANDARD", DriveStateOk, DriveStateOk, DriveStateOk),
+		object("STANDARD", DriveStateOk, DriveStateOk, DriveStateOffline),
+		object("STANDARD", DriveStateOk, DriveStateMissing, DriveStateCorrupt),
+		object("REDUCED_REDUNDANCY", DriveStateOk, DriveStateOk),
+		object("", DriveStateOk, DriveStateCorrupt, DriveStateOk),
+	}
+
+	expected := map[string]Coverage{
+		"STANDARD":           {Full: 1, Degraded: 1, Lost: 1},
+		"REDUCED_REDUNDANCY": {Full: 1},
+		UnknownStorageClass:  {Degraded: 1},
+	}
+	if coverage := CoverageByStorageClass(items); !reflect.DeepEqual(coverage, expected) {
+		t.Errorf("Expected %v, got %v", expected, coverage)
+	}
+}
+
+// Tests ProcessItems processes every item once and stops on the first
+// error.
+func TestProcessItems(t *testing.T) {
+	items := make([]HealResultItem, 100)
+	for i := range items {
+		items[i].ResultIndex = int64(i)
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int64]int)
+	)
+	err := ProcessItems(context.Background(), items, 4, func(ctx context.Context, item HealResultItem) error {
+		mu.Lock()
+		seen[item.ResultIndex]++
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(items) {
+		t.Errorf("Expected %d items processed, got %d", len(items), len(seen))
+	}
+	for index, count := range seen {
+		if count != 1 {
+			t.Errorf("Item %d processed %d times", index, count)
+		}
+	}
+
+	errFailed := errors.New("failed")
+	var calls int32
+	err = ProcessItems(context.Background(), items, 4, func(ctx context.Context, item HealResultItem) error {
+		atomic.AddInt32(&calls, 1)
+		if item.ResultIndex == 10 {
+			return errFailed
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != errFailed {
+		t.Errorf("Expected %v, got %v", errFailed, err)
+	}
+	if n := atomic.LoadInt32(&calls); n >= int32(len(items)) {
+		t.Errorf("Expected the remaining items to be canceled, got %d calls", n)
+	}
+}
+
+// Tests FilterByParityLoss keeps the critically under-redundant
+// objects only.
+func TestFilterByParityLoss(t *testing.T) {
+	object := func(index int64, online, offline int) HealResultItem {
+		item := HealResultItem{ResultIndex: index, Type: HealItemObject, DataBlocks: 2, ParityBlocks: 2}
+		for i := 0; i < online; i++ {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: DriveStateOk})
+		}
+		for i := 0; i < offline; i++ {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: DriveStateOffline})
+		}
+		return item
+	}
+	items := []HealResultItem{
+		{ResultIndex: 1, Type: HealItemBucket, Bucket: "bucket"},
+		object(2, 4, 0),
+		object(3, 3, 1),
+		object(4, 2, 2),
+		object(5, 1, 3),
+		{ResultIndex: 6, Type: HealItemObject},
+	}
+
+	testCases := []struct {
+		maxSpare int
+		indices  []int64
+	}{
+		{maxSpare: 0, indices: []int64{4, 5}},
+		{maxSpare: 1, indices: []int64{3, 4, 5}},
+		{maxSpare: -1, indices: []int64{5}},
+	}
+	for i, testCase := range testCases {
+		var indices []int64
+		for _, item := range FilterByParityLoss(items, testCase.maxSpare) {
+			indices = append(indices, item.ResultIndex)
+		}
+		if !reflect.DeepEqual(indices, testCase.indices) {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.indices, indices)
+		}
+	}
+
+	maxSpare := 0
+	if err := (HealOpts{MinParityLoss: &maxSpare}).Validate(); err == nil {
+		t.Error("Expected error without dry-run")
+	}
+}
+
+// Tests HealWait filters the items of a server ignoring MinParityLoss.
+func TestHealWaitMinParityLossFallback(t *testing.T) {
+	object := func(index int64, online, offline int) HealResultItem {
+		item := HealResultItem{ResultIndex: index, Type: HealItemObject, DataBlocks: 2, ParityBlocks: 2}
+		for i := 0; i < online; i++ {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: DriveStateOk})
+		}
+		for i := 0; i < offline; i++ {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: DriveStateOffline})
+		}
+		return item
+	}
+	maxSpare := 0
+	settings := HealOpts{DryRun: true, Recursive: true, MinParityLoss: &maxSpare}
+	adm := newHealStatusServer(t, 0,
+		HealTaskStatus{Summary: string(HealRunningState), HealSettings: settings, Items: []HealResultItem{object(1, 4, 0), object(2, 2, 2)}},
+		HealTaskStatus{Summary: string(HealFinishedState), HealSettings: settings, Items: []HealResultItem{object(3, 3, 1), object(4, 1, 3)}},
+	)
+
+	status, err := adm.HealWait(context.Background(), "bucket", "", "token", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indices []int64
+	for _, item := range status.Items {
+		indices = append(indices, item.ResultIndex)
+	}
+	if !reflect.DeepEqual(indices, []int64{2, 4}) {
+		t.Errorf("Expected items 2 and 4, got %v", indices)
+	}
+}
+
+// Tests PermissionDeniedDrives reports each permission denied drive
+// of the item once.
+func TestHealResultItemPermissionDeniedDrives(t *testing.T) {
+	item := HealResultItem{Type: HealItemObject}
+	item.Before.Drives = []HealDriveInfo{
+		{Endpoint: "http://server1:9000/d1", State: DriveStateOk},
+		{Endpoint: "http://server2:9000/d1", State: DriveStatePermission},
+	}
+	item.After.Drives = []HealDriveInfo{
+		{Endpoint: "http://server1:9000/d1", State: DriveStateOk},
+		{Endpoint: "http://server2:9000/d1", State: DriveStatePermission},
+	}
+	drives := item.PermissionDeniedDrives()
+	if len(drives) != 1 || drives[0].Endpoint != "http://server2:9000/d1" {
+		t.Errorf("Expected the server2 drive, got %v", drives)
+	}
+}
@@ -0,0 +1,151 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healEventsPollInterval is the interval at which HealEventsSSE polls
+// the status of the heal sequence when the server can't stream it.
+const healEventsPollInterval = time.Second
+
+// maxHealEventSize is the maximum size of a line of a heal event stream.
+const maxHealEventSize = 1 << 20
+
+// HealEventsSSE - starts a heal sequence and sends the items it reports
+// on the returned channel. The server is asked to stream the items as
+// Server-Sent Events, the data of each event is a JSON encoded
+// HealResultItem, comments and other fields are ignored. When the
+// server responds with a regular heal start response instead, the
+// sequence is polled as with HealStream. The item channel is closed
+// once the sequence ends, after which the error channel receives any
+// error before being closed. The sequence is force stopped when ctx is
+// done before it ends, whether its events are streamed or polled.
+func (adm *AdminClient) HealEventsSSE(ctx context.Context, bucket, prefix string, opts HealOpts) (<-chan HealResultItem, <-chan error) {
+	itemCh := make(chan HealResultItem)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		err := adm.healEvents(ctx, bucket, prefix, opts, itemCh)
+		close(itemCh)
+		if err != nil {
+			errCh <- err
+		}
+	}()
+	return itemCh, errCh
+}
+
+func (adm *AdminClient) healEvents(ctx context.Context, bucket, prefix string, opts HealOpts, itemCh chan<- HealResultItem) error {
+	body, queryVals, headers, err := opts.startRequest(prefix)
+	if err != nil {
+		return err
+	}
+	headers.Set("Accept", "text/event-stream")
+	resp, err := adm.executeMethod(ctx, http.MethodPost, requestData{
+		relPath:       healRelPath(bucket, prefix),
+		content:       body,
+		queryValues:   queryVals,
+		customHeaders: headers,
+	})
+	defer closeResponse(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpRespToErrorResponse(resp)
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		err = forwardHealEvents(ctx, bufio.NewScanner(resp.Body), itemCh)
+		adm.stopHealOnDone(ctx, bucket, prefix, opts)
+		return err
+	}
+
+	// The server does not stream heal events, poll the sequence.
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var healStart HealStartSuccess
+	if err = decodeHealResponse(respBytes, &healStart, adm.strictDecoding); err != nil {
+		return err
+	}
+	streamCh, streamErrCh := adm.HealStream(ctx, bucket, prefix, healStart.ClientToken, healEventsPollInterval)
+	for item := range streamCh {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case itemCh <- item:
+		}
+	}
+	return <-streamErrCh
+}
+
+// forwardHealEvents parses the Server-Sent Events read by s and sends
+// the heal result items they hold to itemCh.
+func forwardHealEvents(ctx context.Context, s *bufio.Scanner, itemCh chan<- HealResultItem) error {
+	s.Buffer(make([]byte, 0, 64<<10), maxHealEventSize)
+
+	var data bytes.Buffer
+	dispatch := func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+		defer data.Reset()
+		var item HealResultItem
+		if err := json.Unmarshal(data.Bytes(), &item); err != nil {
+			return ErrUnexpectedResponse("Unable to parse heal event: " + err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case itemCh <- item:
+		}
+		return nil
+	}
+
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			// A blank line ends the event.
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, usually sent as keepalive.
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	// An event is only dispatched once terminated by a blank line.
+	return nil
+}
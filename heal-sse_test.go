@@ -0,0 +1,125 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func collectHealEvents(t *testing.T, itemCh <-chan HealResultItem, errCh <-chan error) []int64 {
+	t.Helper()
+	var indices []int64
+	for item := range itemCh {
+		indices = append(indices, item.ResultIndex)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	return indices
+}
+
+// Tests HealEventsSSE parses a synthetic event stream.
+func TestHealEventsSSE(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Expected event stream to be negotiated, got %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": keepalive\n\n" +
+			"data: {\"resultId\":1,\"type\":\"bucket\"}\n\n" +
+			"event: item\n" +
+			"data: {\"resultId\":2,\n" +
+			"data: \"type\":\"object\"}\n\n" +
+			": keepalive\n" +
+			"\n" +
+			"data:{\"resultId\":3}\n\n"))
+	})
+
+	itemCh, errCh := adm.HealEventsSSE(context.Background(), "bucket", "", HealOpts{Recursive: true})
+	if indices := collectHealEvents(t, itemCh, errCh); !reflect.DeepEqual(indices, []int64{1, 2, 3}) {
+		t.Errorf("Expected items 1 to 3, got %v", indices)
+	}
+}
+
+// Tests HealEventsSSE polls servers which don't stream heal events.
+func TestHealEventsSSEFallback(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("clientToken") == "" {
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+			return
+		}
+		json.NewEncoder(w).Encode(HealTaskStatus{
+			Summary: string(HealFinishedState),
+			Items:   []HealResultItem{{ResultIndex: 1}, {ResultIndex: 2}},
+		})
+	})
+
+	itemCh, errCh := adm.HealEventsSSE(context.Background(), "bucket", "", HealOpts{})
+	if indices := collectHealEvents(t, itemCh, errCh); !reflect.DeepEqual(indices, []int64{1, 2}) {
+		t.Errorf("Expected items 1 and 2, got %v", indices)
+	}
+}
+
+// Tests HealEventsSSE validates the options as Heal does.
+func TestHealEventsSSELatestVersionOnly(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request")
+	})
+
+	itemCh, errCh := adm.HealEventsSSE(context.Background(), "bucket", "", HealOpts{LatestVersionOnly: true})
+	for range itemCh {
+		t.Error("Expected no item")
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected error for a non recursive bucket heal")
+	}
+}
+
+// Tests HealEventsSSE force stops a streamed heal once its context is
+// canceled.
+func TestHealEventsSSEStopOnDone(t *testing.T) {
+	var stopped int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("forceStop") == "true" {
+			atomic.StoreInt32(&stopped, 1)
+			json.NewEncoder(w).Encode(HealStopSuccess{ClientToken: "token"})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"resultId\":1}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	itemCh, errCh := adm.HealEventsSSE(ctx, "bucket", "", HealOpts{Recursive: true})
+	<-itemCh
+	cancel()
+	for range itemCh {
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected the context error")
+	}
+	if atomic.LoadInt32(&stopped) != 1 {
+		t.Error("Expected the heal to be force stopped")
+	}
+}
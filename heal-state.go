@@ -0,0 +1,825 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ETAUnknown is returned by the ETA helpers when the heal rate is
+// zero or cannot be computed.
+const ETAUnknown time.Duration = -1
+
+// bytesProcessed returns the number of bytes the disk has healed or
+// failed to heal so far.
+func (h HealingDisk) bytesProcessed() uint64 {
+	return h.BytesDone + h.BytesFailed
+}
+
+// bytesRemaining returns the number of bytes left to heal on the disk.
+func (h HealingDisk) bytesRemaining() uint64 {
+	if processed := h.bytesProcessed(); processed < h.ObjectsTotalSize {
+		return h.ObjectsTotalSize - processed
+	}
+	return 0
+}
+
+// ETA returns the estimated time left to heal the disk, based on the
+// average rate since the heal started. ETAUnknown is returned when
+// no progress has been made yet.
+func (h HealingDisk) ETA() time.Duration {
+	elapsed := h.LastUpdate.Sub(h.Started)
+	processed := h.bytesProcessed()
+	if elapsed <= 0 || processed == 0 {
+		return ETAUnknown
+	}
+	rate := float64(processed) / elapsed.Seconds()
+	return time.Duration(float64(h.bytesRemaining()) / rate * float64(time.Second))
+}
+
+// ETAAt returns the estimated time left to heal the disk as of now,
+// i.e. the ETA less the time elapsed since the last update, and at
+// least zero. ETAUnknown is returned when no progress has been made
+// yet.
+func (h HealingDisk) ETAAt(now time.Time) time.Duration {
+	eta := h.ETA()
+	if eta == ETAUnknown {
+		return ETAUnknown
+	}
+	if elapsed := now.Sub(h.LastUpdate); elapsed > 0 {
+		eta -= elapsed
+	}
+	if eta < 0 {
+		return 0
+	}
+	return eta
+}
+
+// DiskETA - returns the estimated time left to heal the disk as of the
+// current time of the client, see HealingDisk.ETAAt and SetClock.
+func (adm *AdminClient) DiskETA(h HealingDisk) time.Duration {
+	return h.ETAAt(adm.Now())
+}
+
+// Completed returns true once all the queued buckets have been healed
+// and all the objects of the disk have been processed.
+func (h HealingDisk) Completed() bool {
+	return len(h.QueuedBuckets) == 0 && h.ItemsHealed+h.ItemsFailed >= h.ObjectsTotalCount
+}
+
+// Clone returns a deep copy of the heal information.
+func (h HealingDisk) Clone() HealingDisk {
+	h.QueuedBuckets = cloneStrings(h.QueuedBuckets)
+	h.HealedBuckets = cloneStrings(h.HealedBuckets)
+	return h
+}
+
+// Clone returns a deep copy of the set status.
+func (s SetStatus) Clone() SetStatus {
+	if s.Disks == nil {
+		return s
+	}
+	disks := make([]Disk, len(s.Disks))
+	for i, disk := range s.Disks {
+		if disk.Metrics != nil {
+			metrics := DiskMetrics{}
+			if disk.Metrics.APILatencies != nil {
+				metrics.APILatencies = make(map[string]string, len(disk.Metrics.APILatencies))
+				for k, v := range disk.Metrics.APILatencies {
+					metrics.APILatencies[k] = v
+				}
+			}
+			if disk.Metrics.APICalls != nil {
+				metrics.APICalls = make(map[string]uint64, len(disk.Metrics.APICalls))
+				for k, v := range disk.Metrics.APICalls {
+					metrics.APICalls[k] = v
+				}
+			}
+			disk.Metrics = &metrics
+		}
+		if disk.HealInfo != nil {
+			healInfo := disk.HealInfo.Clone()
+			disk.HealInfo = &healInfo
+		}
+		disks[i] = disk
+	}
+	s.Disks = disks
+	return s
+}
+
+// Clone returns a deep copy of the background heal state, the copy
+// can be modified without affecting b.
+func (b BgHealState) Clone() BgHealState {
+	b.OfflineEndpoints = cloneStrings(b.OfflineEndpoints)
+	b.HealDisks = cloneStrings(b.HealDisks)
+	if b.Decommissioning != nil {
+		b.Decommissioning = append([]int{}, b.Decommissioning...)
+	}
+	if b.Sets != nil {
+		sets := make([]SetStatus, len(b.Sets))
+		for i, set := range b.Sets {
+			sets[i] = set.Clone()
+		}
+		b.Sets = sets
+	}
+	if b.MRF != nil {
+		mrf := make(map[string]MRFStatus, len(b.MRF))
+		for k, v := range b.MRF {
+			mrf[k] = v
+		}
+		b.MRF = mrf
+	}
+	if b.SCParity != nil {
+		parity := make(map[string]int, len(b.SCParity))
+		for k, v := range b.SCParity {
+			parity[k] = v
+		}
+		b.SCParity = parity
+	}
+	return b
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+// healingDisks returns the heal information of all the disks currently
+// healing, keyed by disk ID.
+func (b BgHealState) healingDisks() map[string]HealingDisk {
+	disks := make(map[string]HealingDisk)
+	for _, set := range b.Sets {
+		for _, disk := range set.Disks {
+			if disk.HealInfo != nil {
+				disks[disk.HealInfo.ID] = *disk.HealInfo
+			}
+		}
+	}
+	return disks
+}
+
+// MatchDisks pairs the disks of two snapshots by their ID rather than
+// by their index, which changes when pools are added or removed. The
+// pairs are keyed by disk ID, with the disk of prev first and the disk
+// of cur second, nil where the disk is absent from a snapshot. The
+// pointers refer to the elements of prev and cur. Disks without an ID
+// are skipped.
+func MatchDisks(prev, cur []HealingDisk) map[string][2]*HealingDisk {
+	pairs := make(map[string][2]*HealingDisk, len(cur))
+	for i := range prev {
+		if id := prev[i].ID; id != "" {
+			pair := pairs[id]
+			pair[0] = &prev[i]
+			pairs[id] = pair
+		}
+	}
+	for i := range cur {
+		if id := cur[i].ID; id != "" {
+			pair := pairs[id]
+			pair[1] = &cur[i]
+			pairs[id] = pair
+		}
+	}
+	return pairs
+}
+
+// ClusterETA returns the estimated time left to heal all the healing
+// disks of the cluster. The heal rate is computed from the progress
+// made by the disks present in both b and prev, a snapshot taken
+// elapsed before b. ETAUnknown is returned when no progress was made
+// in between the snapshots.
+func (b BgHealState) ClusterETA(prev BgHealState, elapsed time.Duration) time.Duration {
+	if elapsed <= 0 {
+		return ETAUnknown
+	}
+	prevDisks := prev.healingDisks()
+
+	var remaining, progress uint64
+	for id, disk := range b.healingDisks() {
+		remaining += disk.bytesRemaining()
+		if prevDisk, ok := prevDisks[id]; ok && disk.bytesProcessed() > prevDisk.bytesProcessed() {
+			progress += disk.bytesProcessed() - prevDisk.bytesProcessed()
+		}
+	}
+	if progress == 0 {
+		return ETAUnknown
+	}
+	rate := float64(progress) / elapsed.Seconds()
+	return time.Duration(float64(remaining) / rate * float64(time.Second))
+}
+
+// MRFBacklogAges returns, for each endpoint, the time elapsed between
+// the start of its MRF healing and now. Endpoints which did not start
+// MRF healing are skipped.
+func (b BgHealState) MRFBacklogAges(now time.Time) map[string]time.Duration {
+	ages := make(map[string]time.Duration, len(b.MRF))
+	for endpoint, mrf := range b.MRF {
+		if !mrf.Started.IsZero() {
+			ages[endpoint] = now.Sub(mrf.Started)
+		}
+	}
+	return ages
+}
+
+// TotalHealed returns the number of items healed by the healing
+// disks and by MRF across all the endpoints.
+func (b BgHealState) TotalHealed() uint64 {
+	var total uint64
+	for _, disk := range b.healingDisks() {
+		total += disk.ItemsHealed
+	}
+	for _, mrf := range b.MRF {
+		total += mrf.ItemsHealed
+	}
+	return total
+}
+
+// TotalFailed returns the number of items the healing disks failed to
+// heal. MRF does not report failed items.
+func (b BgHealState) TotalFailed() uint64 {
+	var total uint64
+	for _, disk := range b.healingDisks() {
+		total += disk.ItemsFailed
+	}
+	return total
+}
+
+// FailureRate returns the fraction of the processed items which failed
+// to heal, between 0 and 1. Zero is returned when no item has been
+// processed yet.
+func (b BgHealState) FailureRate() float64 {
+	failed := b.TotalFailed()
+	processed := b.TotalHealed() + failed
+	if processed == 0 {
+		return 0
+	}
+	return float64(failed) / float64(processed)
+}
+
+// StuckBuckets returns the sorted buckets queued for healing on any
+// disk but healed on none, e.g. buckets left over by an interrupted
+// heal.
+func (b BgHealState) StuckBuckets() []string {
+	queued := make(map[string]struct{})
+	healed := make(map[string]struct{})
+	for _, disk := range b.healingDisks() {
+		for _, bucket := range disk.QueuedBuckets {
+			queued[bucket] = struct{}{}
+		}
+		for _, bucket := range disk.HealedBuckets {
+			healed[bucket] = struct{}{}
+		}
+	}
+	var buckets []string
+	for bucket := range queued {
+		if _, ok := healed[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+	}
+	sort.Strings(buckets)
+	return buckets
+}
+
+// PermissionDeniedDrives returns the drives of the sets in the
+// DriveStatePermission state, typically because of wrong ownership or
+// mode of their mount point. Their heal information is returned when
+// reported, otherwise it is filled with the ID, location and path of
+// the drive.
+func (b BgHealState) PermissionDeniedDrives() []HealingDisk {
+	var drives []HealingDisk
+	for _, set := range b.Sets {
+		for _, disk := range set.Disks {
+			if disk.State != DriveStatePermission {
+				continue
+			}
+			if disk.HealInfo != nil {
+				drives = append(drives, disk.HealInfo.Clone())
+				continue
+			}
+			drives = append(drives, HealingDisk{
+				ID:        disk.UUID,
+				PoolIndex: disk.PoolIndex,
+				SetIndex:  disk.SetIndex,
+				DiskIndex: disk.DiskIndex,
+				Endpoint:  disk.Endpoint,
+				Path:      disk.DrivePath,
+			})
+		}
+	}
+	return drives
+}
+
+// DriveState returns the state of the disk as a DriveState, disks
+// without a state are in the DriveStateUnknown state.
+func (d Disk) DriveState() DriveState {
+	if d.State == "" {
+		return DriveStateUnknown
+	}
+	return DriveState(d.State)
+}
+
+// HealDriveInfos returns the disks of the set as HealDriveInfo, as
+// reported in heal results, to analyze both with the same helpers.
+func (s SetStatus) HealDriveInfos() []HealDriveInfo {
+	drives := make([]HealDriveInfo, 0, len(s.Disks))
+	for _, disk := range s.Disks {
+		drives = append(drives, HealDriveInfo{
+			UUID:     disk.UUID,
+			Endpoint: disk.Endpoint,
+			State:    string(disk.DriveState()),
+		})
+	}
+	return drives
+}
+
+// DriveHealth returns the number of online drives of the set and the
+// total number of drives in the set.
+func (s SetStatus) DriveHealth() (online, total int) {
+	for _, disk := range s.Disks {
+		if disk.State == DriveStateOk {
+			online++
+		}
+	}
+	return online, len(s.Disks)
+}
+
+// DriveStateHistogram returns the number of drives of the cluster in
+// each state. Drives without a state are counted as offline when they
+// belong to an offline node, and as unknown otherwise.
+func (b BgHealState) DriveStateHistogram() map[DriveState]int {
+	histogram := make(map[DriveState]int)
+	for _, set := range b.Sets {
+		for _, disk := range set.Disks {
+			state := DriveState(disk.State)
+			if state == "" {
+				state = DriveStateUnknown
+				for _, node := range b.OfflineEndpoints {
+					if node != "" && strings.HasPrefix(disk.Endpoint, node) {
+						state = DriveStateOffline
+						break
+					}
+				}
+			}
+			histogram[state]++
+		}
+	}
+	return histogram
+}
+
+// isDecommissioning returns true if the pool is being decommissioned.
+func (b BgHealState) isDecommissioning(pool int) bool {
+	for _, p := range b.Decommissioning {
+		if p == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPoolBusy returns true if the pool is being decommissioned or if
+// any of its drives is healing. Servers which don't report
+// decommissioning are only checked for healing drives.
+func (b BgHealState) IsPoolBusy(pool int) bool {
+	if b.isDecommissioning(pool) {
+		return true
+	}
+	for _, set := range b.Sets {
+		if set.PoolIndex != pool {
+			continue
+		}
+		for _, disk := range set.Disks {
+			if disk.Healing || disk.HealInfo != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StandardParity returns the parity of the STANDARD storage class, or
+// zero when it is unknown. Sets don't report the storage class of
+// their objects, this is the parity assumed for every set.
+func (b BgHealState) StandardParity() int {
+	return b.SCParity["STANDARD"]
+}
+
+// MostDegradedSet returns the set closest to losing read quorum, that
+// is the set with the fewest online drives relative to the STANDARD
+// parity, see StandardParity. false is returned when there are no sets.
+func (b BgHealState) MostDegradedSet() (SetStatus, bool) {
+	var (
+		worst       SetStatus
+		worstMargin int
+		found       bool
+	)
+	parity := b.StandardParity()
+	for _, set := range b.Sets {
+		online, total := set.DriveHealth()
+		// Number of drives which can still be lost before
+		// the set can't serve reads anymore.
+		margin := online - (total - parity)
+		if !found || margin < worstMargin {
+			worst, worstMargin, found = set, margin, true
+		}
+	}
+	return worst, found
+}
+
+// DriveRow - heal state of a single drive, see BgHealState.DriveRows.
+type DriveRow struct {
+	PoolIndex   int       `json:"pool_index"`
+	SetIndex    int       `json:"set_index"`
+	DiskIndex   int       `json:"disk_index"`
+	Endpoint    string    `json:"endpoint"`
+	State       string    `json:"state"`
+	ItemsHealed uint64    `json:"items_healed"`
+	ItemsFailed uint64    `json:"items_failed"`
+	BytesDone   uint64    `json:"bytes_done"`
+	LastUpdate  time.Time `json:"last_update"`
+}
+
+// DriveRows returns one row per drive of the cluster, ordered as in
+// Sets. The heal fields of drives which are not healing are zero.
+func (b BgHealState) DriveRows() []DriveRow {
+	var rows []DriveRow
+	for _, set := range b.Sets {
+		for _, disk := range set.Disks {
+			row := DriveRow{
+				PoolIndex: set.PoolIndex,
+				SetIndex:  set.SetIndex,
+				DiskIndex: disk.DiskIndex,
+				Endpoint:  disk.Endpoint,
+				State:     disk.State,
+			}
+			if h := disk.HealInfo; h != nil {
+				row.ItemsHealed = h.ItemsHealed
+				row.ItemsFailed = h.ItemsFailed
+				row.BytesDone = h.BytesDone
+				row.LastUpdate = h.LastUpdate
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// EstimatedReadBytes estimates the number of bytes read to complete
+// the heal of all the healing disks. Rebuilding the shards of an object
+// on one disk requires reading DataBlocks shards, that is about the
+// object size, hence the estimate is the size of the objects left to
+// heal on each healing disk, as reported by the server.
+func (b BgHealState) EstimatedReadBytes() uint64 {
+	var total uint64
+	for _, disk := range b.healingDisks() {
+		total += disk.bytesRemaining()
+	}
+	return total
+}
+
+// ParityConsistent checks that the parity reported for each storage
+// class is compatible with every set: all the sets of a pool must
+// have the same number of drives, and the parity of a storage class
+// can't exceed half of the drives of a set. The IDs of the sets which
+// disagree are returned.
+func (b BgHealState) ParityConsistent() (bool, []string) {
+	// Most common drive count of the sets of each pool.
+	counts := make(map[int]map[int]int)
+	for _, set := range b.Sets {
+		if counts[set.PoolIndex] == nil {
+			counts[set.PoolIndex] = make(map[int]int)
+		}
+		counts[set.PoolIndex][len(set.Disks)]++
+	}
+	poolDrives := make(map[int]int, len(counts))
+	for pool, drives := range counts {
+		best := -1
+		for n, c := range drives {
+			if best < 0 || c > drives[best] || (c == drives[best] && n > best) {
+				best = n
+			}
+		}
+		poolDrives[pool] = best
+	}
+
+	var inconsistent []string
+	for _, set := range b.Sets {
+		ok := len(set.Disks) == poolDrives[set.PoolIndex]
+		for _, parity := range b.SCParity {
+			if parity > len(set.Disks)/2 {
+				ok = false
+			}
+		}
+		if !ok {
+			inconsistent = append(inconsistent, set.ID)
+		}
+	}
+	return len(inconsistent) == 0, inconsistent
+}
+
+// ErrHealDiskNotFound is returned by WaitForDiskHeal when the disk is
+// not part of the background heal status.
+var ErrHealDiskNotFound = errors.New("disk not found in background heal status")
+
+// ErrHealDiskNotHealing is returned by WaitForDiskHeal when the disk is
+// part of the background heal status but is not healing.
+var ErrHealDiskNotHealing = errors.New("disk is not healing")
+
+// WaitForDiskHeal - polls the background heal status every pollInterval
+// until the heal of the disk with the given ID is completed and returns
+// its final heal information. Servers stop reporting heal information
+// once a disk is healed, a disk which was seen healing and is present
+// without heal information is reported as completed as well, whereas
+// ErrHealDiskNotHealing is returned if it was never seen healing.
+// ErrHealDiskNotFound is returned if the disk is not part of the
+// status anymore. A poll interval which is not positive is rejected.
+func (adm *AdminClient) WaitForDiskHeal(ctx context.Context, diskID string, pollInterval time.Duration) (HealingDisk, error) {
+	if pollInterval <= 0 {
+		return HealingDisk{}, ErrInvalidArgument("heal poll interval must be positive")
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var (
+		last HealingDisk
+		seen bool
+	)
+	for {
+		state, err := adm.BackgroundHealStatus(ctx)
+		if err != nil {
+			return last, err
+		}
+
+		found := false
+		for _, set := range state.Sets {
+			for _, disk := range set.Disks {
+				if disk.HealInfo != nil && disk.HealInfo.ID == diskID {
+					last, seen, found = *disk.HealInfo, true, true
+					if last.Completed() {
+						return last, nil
+					}
+				} else if disk.HealInfo == nil && disk.UUID == diskID {
+					if seen {
+						return last, nil
+					}
+					return last, ErrHealDiskNotHealing
+				}
+			}
+		}
+		if !found {
+			return last, ErrHealDiskNotFound
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// HealPreflightResult - result of HealPreflight.
+type HealPreflightResult struct {
+	// Advisable is true if no set is healing the target bucket.
+	Advisable bool `json:"advisable"`
+	// IDs of the sets with a drive healing the target bucket.
+	ConflictingSets []string `json:"conflictingSets,omitempty"`
+}
+
+// HealPreflight - checks the background heal status for drives healing
+// bucket, a manual heal of the same bucket would contend with them
+// for locks and is better started once they are done. Background heal
+// walks whole buckets, conflicts are detected per bucket and prefix is
+// accepted for symmetry with Heal. An empty bucket conflicts with any
+// healing drive. A paused background heal never conflicts.
+func (adm *AdminClient) HealPreflight(ctx context.Context, bucket, prefix string) (HealPreflightResult, error) {
+	state, err := adm.BackgroundHealStatus(ctx)
+	if err != nil {
+		return HealPreflightResult{}, err
+	}
+	result := HealPreflightResult{Advisable: true}
+	if state.Paused {
+		return result, nil
+	}
+	for _, set := range state.Sets {
+		for _, disk := range set.Disks {
+			if h := disk.HealInfo; h != nil && !h.Completed() && (bucket == "" || h.Bucket == bucket) {
+				result.ConflictingSets = append(result.ConflictingSets, set.ID)
+				break
+			}
+		}
+	}
+	result.Advisable = len(result.ConflictingSets) == 0
+	return result, nil
+}
+
+// EndpointTracker - tracks for how long the offline endpoints of the
+// background heal states it observes have continuously been offline,
+// to tell restarts apart from persistent failures. It is safe for
+// concurrent use, its zero value is ready to use.
+type EndpointTracker struct {
+	mu           sync.Mutex
+	offlineSince map[string]time.Time
+	lastObserved time.Time
+
+	// now returns the current time, time.Now when nil.
+	now func() time.Time
+}
+
+func (t *EndpointTracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// Observe records the offline endpoints of state. Endpoints not offline
+// in state are forgotten, their offline time restarts from zero when
+// they go offline again.
+func (t *EndpointTracker) Observe(state BgHealState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock()
+	offlineSince := make(map[string]time.Time, len(state.OfflineEndpoints))
+	for _, endpoint := range state.OfflineEndpoints {
+		since, ok := t.offlineSince[endpoint]
+		if !ok {
+			since = now
+		}
+		offlineSince[endpoint] = since
+	}
+	t.offlineSince = offlineSince
+	t.lastObserved = now
+}
+
+// PersistentlyOffline returns the endpoints which have been offline in
+// all the observations made over at least threshold, sorted. The time
+// offline is measured from the first observation of the endpoint
+// offline to the last observation.
+func (t *EndpointTracker) PersistentlyOffline(threshold time.Duration) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var endpoints []string
+	for endpoint, since := range t.offlineSince {
+		if t.lastObserved.Sub(since) >= threshold {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// AtomicCounter - a monotonically increasing counter safe for
+// concurrent use.
+type AtomicCounter struct {
+	v uint64
+}
+
+// Load returns the value of the counter.
+func (c *AtomicCounter) Load() uint64 {
+	return atomic.LoadUint64(&c.v)
+}
+
+func (c *AtomicCounter) add(delta uint64) {
+	atomic.AddUint64(&c.v, delta)
+}
+
+// HealBytesCounter - polls the background heal status every poll and
+// counts the bytes healed by the healing disks since the first poll.
+// The progress of each disk is tracked separately: a disk whose
+// counters were reset, e.g. by a server restart, is counted from zero
+// again and the counter never goes backwards. A disk missing from some
+// polls is counted from the last progress seen for it, and a disk
+// first seen after the first poll, e.g. as its node was offline, from
+// its progress when first seen. Failed polls
+// are skipped. Polling stops when ctx is canceled or the returned
+// function is called, which waits for the polling to end. A poll
+// interval which is not positive is rejected.
+func (adm *AdminClient) HealBytesCounter(ctx context.Context, poll time.Duration) (*AtomicCounter, func(), error) {
+	if poll <= 0 {
+		return nil, nil, ErrInvalidArgument("heal poll interval must be positive")
+	}
+	counter := &AtomicCounter{}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+
+		// Last progress seen for each disk, kept while the disk is
+		// missing from a poll. The first progress seen for a disk
+		// is its baseline.
+		last := make(map[string]uint64)
+		for {
+			if state, err := adm.BackgroundHealStatus(ctx); err == nil {
+				for id, disk := range state.healingDisks() {
+					prev, known := last[id]
+					last[id] = disk.BytesDone
+					switch {
+					case !known:
+					case disk.BytesDone >= prev:
+						counter.add(disk.BytesDone - prev)
+					default:
+						counter.add(disk.BytesDone)
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	return counter, stop, nil
+}
+
+// PendingItems returns the number of items left to process by the
+// healing disks and by MRF.
+func (b BgHealState) PendingItems() uint64 {
+	var pending uint64
+	for _, disk := range b.healingDisks() {
+		if processed := disk.ItemsHealed + disk.ItemsFailed; processed < disk.ObjectsTotalCount {
+			pending += disk.ObjectsTotalCount - processed
+		}
+	}
+	for _, mrf := range b.MRF {
+		if mrf.ItemsHealed < mrf.TotalItems {
+			pending += mrf.TotalItems - mrf.ItemsHealed
+		}
+	}
+	return pending
+}
+
+// HealStateComparison - differences between the heal states of two
+// clusters, see CompareHealState. Pairs hold the value of the first
+// cluster followed by the value of the second one.
+type HealStateComparison struct {
+	// DriveStates holds the drive states counted differently.
+	DriveStates  map[DriveState][2]int `json:"driveStates,omitempty"`
+	FailureRates [2]float64            `json:"failureRates"`
+	PendingItems [2]uint64             `json:"pendingItems"`
+}
+
+// CompareHealState compares the heal states of two clusters, e.g. the
+// source and destination of a migration: their drive state histograms,
+// see BgHealState.DriveStateHistogram, their failure rates and their
+// pending items.
+func CompareHealState(a, b BgHealState) HealStateComparison {
+	c := HealStateComparison{
+		DriveStates:  make(map[DriveState][2]int),
+		FailureRates: [2]float64{a.FailureRate(), b.FailureRate()},
+		PendingItems: [2]uint64{a.PendingItems(), b.PendingItems()},
+	}
+	histA, histB := a.DriveStateHistogram(), b.DriveStateHistogram()
+	for state, count := range histA {
+		if histB[state] != count {
+			c.DriveStates[state] = [2]int{count, histB[state]}
+		}
+	}
+	for state, count := range histB {
+		if _, ok := histA[state]; !ok {
+			c.DriveStates[state] = [2]int{0, count}
+		}
+	}
+	return c
+}
+
+// Degraded returns true if the second cluster is in a worse heal state
+// than the first one: with fewer drives in the DriveStateOk state, a
+// higher failure rate or more pending items. A migration to a degraded
+// cluster should not be cut over.
+func (c HealStateComparison) Degraded() bool {
+	ok := c.DriveStates[DriveState(DriveStateOk)]
+	return ok[1] < ok[0] || c.FailureRates[1] > c.FailureRates[0] || c.PendingItems[1] > c.PendingItems[0]
+}
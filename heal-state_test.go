@@ -0,0 +1,595 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func healStateWithDisks(disks ...HealingDisk) BgHealState {
+	set := SetStatus{ID: "pool-0-set-0"}
+	for i := range disks {
+		set.Disks = append(set.Disks, Disk{HealInfo: &disks[i]})
+	}
+	return BgHealState{Sets: []SetStatus{set}}
+}
+
+// Tests ClusterETA with steady progress and with a stalled heal.
+func TestClusterETA(t *testing.T) {
+	prev := healStateWithDisks(
+		HealingDisk{ID: "disk1", ObjectsTotalSize: 1000, BytesDone: 100},
+		HealingDisk{ID: "disk2", ObjectsTotalSize: 1000, BytesDone: 200},
+	)
+	cur := healStateWithDisks(
+		HealingDisk{ID: "disk1", ObjectsTotalSize: 1000, BytesDone: 150},
+		HealingDisk{ID: "disk2", ObjectsTotalSize: 1000, BytesDone: 240, BytesFailed: 10},
+	)
+
+	// 100 bytes healed in 10s, 1600 bytes remaining.
+	if eta := cur.ClusterETA(prev, 10*time.Second); eta != 160*time.Second {
+		t.Errorf("Expected 160s, got %v", eta)
+	}
+	if eta := cur.ClusterETA(cur, 10*time.Second); eta != ETAUnknown {
+		t.Errorf("Expected unknown ETA for stalled heal, got %v", eta)
+	}
+}
+
+// Tests MRFBacklogAges skips endpoints without a start time.
+func TestMRFBacklogAges(t *testing.T) {
+	now := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	state := BgHealState{MRF: map[string]MRFStatus{
+		"server1:9000": {Started: now.Add(-time.Minute)},
+		"server2:9000": {Started: now.Add(-time.Hour)},
+		"server3:9000": {},
+	}}
+	expected := map[string]time.Duration{
+		"server1:9000": time.Minute,
+		"server2:9000": time.Hour,
+	}
+	if ages := state.MRFBacklogAges(now); !reflect.DeepEqual(ages, expected) {
+		t.Errorf("Expected %v, got %v", expected, ages)
+	}
+}
+
+// Tests Disk states map to the same DriveState as heal results.
+func TestDiskDriveState(t *testing.T) {
+	testCases := []struct {
+		state    string
+		expected DriveState
+	}{
+		{DriveStateOk, DriveState(DriveStateOk)},
+		{DriveStateOffline, DriveStateOffline},
+		{DriveStateCorrupt, DriveStateCorrupt},
+		{DriveStateMissing, DriveStateMissing},
+		{DriveStatePermission, DriveStatePermission},
+		{DriveStateFaulty, DriveStateFaulty},
+		{DriveStateUnknown, DriveStateUnknown},
+		{DriveStateUnformatted, DriveStateUnformatted},
+		{"", DriveStateUnknown},
+	}
+	for i, testCase := range testCases {
+		if state := (Disk{State: testCase.state}).DriveState(); state != testCase.expected {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.expected, state)
+		}
+	}
+
+	set := SetStatus{Disks: []Disk{
+		{UUID: "u1", Endpoint: "http://server1/disk1", State: DriveStateOk},
+		{UUID: "u2", Endpoint: "http://server2/disk1", State: DriveStateFaulty},
+	}}
+	drives := set.HealDriveInfos()
+	expected := []HealDriveInfo{
+		{UUID: "u1", Endpoint: "http://server1/disk1", State: DriveStateOk},
+		{UUID: "u2", Endpoint: "http://server2/disk1", State: DriveStateFaulty},
+	}
+	if !reflect.DeepEqual(drives, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, drives)
+	}
+	if !drives[0].Healthy() || !drives[1].NeedsAttention() {
+		t.Error("Expected heal result helpers to apply to set drives")
+	}
+}
+
+// Tests DriveStateHistogram with a mix of drive states.
+func TestDriveStateHistogram(t *testing.T) {
+	state := BgHealState{
+		OfflineEndpoints: []string{"http://server3:9000"},
+		Sets: []SetStatus{
+			{Disks: []Disk{
+				{Endpoint: "http://server1:9000/disk1", State: DriveStateOk},
+				{Endpoint: "http://server1:9000/disk2", State: DriveStateOk},
+				{Endpoint: "http://server2:9000/disk1", State: DriveStateFaulty},
+			}},
+			{Disks: []Disk{
+				{Endpoint: "http://server2:9000/disk2", State: DriveStateOk},
+				{Endpoint: "http://server3:9000/disk1"},
+				{Endpoint: "http://server4:9000/disk1"},
+				{Endpoint: "http://server4:9000/disk2", State: DriveStateUnformatted},
+			}},
+		},
+	}
+	expected := map[DriveState]int{
+		DriveState(DriveStateOk): 3,
+		DriveStateFaulty:         1,
+		DriveStateOffline:        1,
+		DriveStateUnknown:        1,
+		DriveStateUnformatted:    1,
+	}
+	if histogram := state.DriveStateHistogram(); !reflect.DeepEqual(histogram, expected) {
+		t.Errorf("Expected %v, got %v", expected, histogram)
+	}
+}
+
+// Tests IsPoolBusy combines decommissioning and healing.
+func TestIsPoolBusy(t *testing.T) {
+	state := BgHealState{
+		Decommissioning: []int{2},
+		Sets: []SetStatus{
+			{PoolIndex: 0, Disks: []Disk{{State: DriveStateOk}}},
+			{PoolIndex: 1, Disks: []Disk{{State: DriveStateOk}, {State: DriveStateOk, HealInfo: &HealingDisk{}}}},
+			{PoolIndex: 2, Disks: []Disk{{State: DriveStateOk}}},
+		},
+	}
+	for pool, busy := range []bool{false, true, true, false} {
+		if state.IsPoolBusy(pool) != busy {
+			t.Errorf("Pool %d: Expected busy to be %v", pool, busy)
+		}
+	}
+
+	// Older servers don't report decommissioning.
+	var older BgHealState
+	if err := json.Unmarshal([]byte(`{"sets":[{"pool_index":0,"disks":[{"state":"ok","healing":true}]}]}`), &older); err != nil {
+		t.Fatal(err)
+	}
+	if !older.IsPoolBusy(0) || older.IsPoolBusy(1) {
+		t.Error("Expected only the healing pool to be busy")
+	}
+}
+
+// Tests MostDegradedSet with sets of varying health.
+func TestMostDegradedSet(t *testing.T) {
+	disks := func(states ...string) []Disk {
+		var ds []Disk
+		for _, state := range states {
+			ds = append(ds, Disk{State: state})
+		}
+		return ds
+	}
+
+	if _, ok := (BgHealState{}).MostDegradedSet(); ok {
+		t.Error("Expected no set without sets")
+	}
+
+	state := BgHealState{
+		SCParity: map[string]int{"STANDARD": 2},
+		Sets: []SetStatus{
+			{ID: "healthy", Disks: disks(DriveStateOk, DriveStateOk, DriveStateOk, DriveStateOk)},
+			{ID: "one-offline", Disks: disks(DriveStateOk, DriveStateOffline, DriveStateOk, DriveStateOk)},
+			{ID: "two-offline", Disks: disks(DriveStateOffline, DriveStateOk, DriveStateUnformatted, DriveStateOk)},
+			{ID: "one-missing", Disks: disks(DriveStateOk, DriveStateOk, DriveStateOk, DriveStateMissing)},
+		},
+	}
+	set, ok := state.MostDegradedSet()
+	if !ok {
+		t.Fatal("Expected a set")
+	}
+	if set.ID != "two-offline" {
+		t.Errorf("Expected two-offline, got %s", set.ID)
+	}
+	if parity := state.StandardParity(); parity != 2 {
+		t.Errorf("Expected STANDARD parity 2, got %d", parity)
+	}
+	if parity := (BgHealState{}).StandardParity(); parity != 0 {
+		t.Errorf("Expected unknown parity, got %d", parity)
+	}
+}
+
+// Tests DriveRows flattening of healing and non healing drives.
+func TestDriveRows(t *testing.T) {
+	now := time.Now().UTC()
+	state := BgHealState{
+		Sets: []SetStatus{
+			{PoolIndex: 0, SetIndex: 0, Disks: []Disk{
+				{Endpoint: "http://server1/disk1", State: DriveStateOk, DiskIndex: 0},
+				{Endpoint: "http://server1/disk2", State: DriveStateOk, DiskIndex: 1, HealInfo: &HealingDisk{
+					ItemsHealed: 10, ItemsFailed: 1, BytesDone: 1024, LastUpdate: now,
+				}},
+			}},
+			{PoolIndex: 1, SetIndex: 2, Disks: []Disk{
+				{Endpoint: "http://server2/disk1", State: DriveStateOffline},
+			}},
+		},
+	}
+
+	rows := state.DriveRows()
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	expected := DriveRow{
+		DiskIndex:   1,
+		Endpoint:    "http://server1/disk2",
+		State:       DriveStateOk,
+		ItemsHealed: 10,
+		ItemsFailed: 1,
+		BytesDone:   1024,
+		LastUpdate:  now,
+	}
+	if rows[1] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rows[1])
+	}
+	if rows[2].PoolIndex != 1 || rows[2].SetIndex != 2 || rows[2].ItemsHealed != 0 {
+		t.Errorf("Unexpected row %+v", rows[2])
+	}
+}
+
+// Tests EstimatedReadBytes over healing disks.
+func TestEstimatedReadBytes(t *testing.T) {
+	state := healStateWithDisks(
+		HealingDisk{ID: "disk1", ObjectsTotalSize: 1000, BytesDone: 400},
+		HealingDisk{ID: "disk2", ObjectsTotalSize: 1000, BytesDone: 900, BytesFailed: 100},
+	)
+	if n := state.EstimatedReadBytes(); n != 600 {
+		t.Errorf("Expected 600 bytes, got %d", n)
+	}
+}
+
+// Tests ParityConsistent with one inconsistent set.
+func TestParityConsistent(t *testing.T) {
+	state := BgHealState{
+		SCParity: map[string]int{"STANDARD": 2, "REDUCED_REDUNDANCY": 1},
+		Sets: []SetStatus{
+			{ID: "pool-0-set-0", Disks: make([]Disk, 4)},
+			{ID: "pool-0-set-1", Disks: make([]Disk, 4)},
+			{ID: "pool-0-set-2", Disks: make([]Disk, 3)},
+			{ID: "pool-1-set-0", PoolIndex: 1, Disks: make([]Disk, 6)},
+		},
+	}
+	ok, sets := state.ParityConsistent()
+	if ok {
+		t.Error("Expected inconsistent parity")
+	}
+	if !reflect.DeepEqual(sets, []string{"pool-0-set-2"}) {
+		t.Errorf("Unexpected inconsistent sets %v", sets)
+	}
+
+	state.Sets = state.Sets[:2]
+	if ok, sets = state.ParityConsistent(); !ok || len(sets) != 0 {
+		t.Errorf("Expected consistent parity, got %v", sets)
+	}
+}
+
+// Tests WaitForDiskHeal across polls until the disk heal completes.
+func TestWaitForDiskHeal(t *testing.T) {
+	states := []HealingDisk{
+		{ID: "disk", ObjectsTotalCount: 10, ItemsHealed: 2, QueuedBuckets: []string{"a", "b"}},
+		{ID: "disk", ObjectsTotalCount: 10, ItemsHealed: 6, QueuedBuckets: []string{"b"}},
+		{ID: "disk", ObjectsTotalCount: 10, ItemsHealed: 9, ItemsFailed: 1},
+	}
+	var polls int
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		state := healStateWithDisks(states[polls])
+		if polls < len(states)-1 {
+			polls++
+		}
+		json.NewEncoder(w).Encode(state)
+	})
+
+	disk, err := adm.WaitForDiskHeal(context.Background(), "disk", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !disk.Completed() || disk.ItemsHealed != 9 {
+		t.Errorf("Unexpected final heal information %+v", disk)
+	}
+
+	if _, err = adm.WaitForDiskHeal(context.Background(), "other", time.Millisecond); !errors.Is(err, ErrHealDiskNotFound) {
+		t.Errorf("Expected ErrHealDiskNotFound, got %v", err)
+	}
+	if _, err = adm.WaitForDiskHeal(context.Background(), "disk", 0); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+}
+
+// Tests WaitForDiskHeal returns at once for a disk which is not healing.
+func TestWaitForDiskHealNotHealing(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(BgHealState{Sets: []SetStatus{{Disks: []Disk{{UUID: "disk", State: DriveStateOk}}}}})
+	})
+
+	if _, err := adm.WaitForDiskHeal(context.Background(), "disk", time.Millisecond); !errors.Is(err, ErrHealDiskNotHealing) {
+		t.Errorf("Expected ErrHealDiskNotHealing, got %v", err)
+	}
+}
+
+// Tests HealPreflight advises waiting while the target set is healing.
+func TestHealPreflight(t *testing.T) {
+	state := BgHealState{
+		Sets: []SetStatus{
+			{ID: "pool-0-set-0", Disks: []Disk{{HealInfo: &HealingDisk{ObjectsTotalCount: 10, Bucket: "bucket"}}}},
+			{ID: "pool-0-set-1", Disks: []Disk{{HealInfo: &HealingDisk{ObjectsTotalCount: 10, Bucket: "other"}}}},
+			{ID: "pool-0-set-2", Disks: []Disk{{State: DriveStateOk}}},
+		},
+	}
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(state)
+	})
+
+	result, err := adm.HealPreflight(context.Background(), "bucket", "prefix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Advisable || !reflect.DeepEqual(result.ConflictingSets, []string{"pool-0-set-0"}) {
+		t.Errorf("Expected to wait for pool-0-set-0, got %+v", result)
+	}
+
+	result, err = adm.HealPreflight(context.Background(), "idle", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Advisable || len(result.ConflictingSets) != 0 {
+		t.Errorf("Expected heal to be advisable, got %+v", result)
+	}
+}
+
+// Tests EndpointTracker with an endpoint flapping and one staying
+// offline.
+func TestEndpointTracker(t *testing.T) {
+	now := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	tracker := &EndpointTracker{now: func() time.Time { return now }}
+
+	observations := [][]string{
+		{"server1:9000", "server2:9000"},
+		{"server2:9000"},
+		{"server1:9000", "server2:9000"},
+		{"server1:9000", "server2:9000"},
+	}
+	for _, offline := range observations {
+		tracker.Observe(BgHealState{OfflineEndpoints: offline})
+		now = now.Add(30 * time.Second)
+	}
+
+	// server2 has been offline for 90s, server1 for 30s since it came
+	// back online.
+	if endpoints := tracker.PersistentlyOffline(time.Minute); !reflect.DeepEqual(endpoints, []string{"server2:9000"}) {
+		t.Errorf("Expected server2 to be persistently offline, got %v", endpoints)
+	}
+	if endpoints := tracker.PersistentlyOffline(30 * time.Second); len(endpoints) != 2 {
+		t.Errorf("Expected both endpoints offline for 30s, got %v", endpoints)
+	}
+
+	tracker.Observe(BgHealState{})
+	if endpoints := tracker.PersistentlyOffline(0); len(endpoints) != 0 {
+		t.Errorf("Expected no offline endpoints, got %v", endpoints)
+	}
+}
+
+// Tests HealBytesCounter across increasing totals and a reset.
+func TestHealBytesCounter(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	// The disks are missing from the third poll, the late disk is
+	// first seen on the second poll.
+	disks := [][]HealingDisk{
+		{{ID: "disk", BytesDone: 100}},
+		{{ID: "disk", BytesDone: 250}, {ID: "late", BytesDone: 1000}},
+		nil,
+		{{ID: "disk", BytesDone: 400}, {ID: "late", BytesDone: 1100}},
+		{{ID: "disk", BytesDone: 50}, {ID: "late", BytesDone: 1100}},
+	}
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polled := disks[polls]
+		if polls < len(disks)-1 {
+			polls++
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(healStateWithDisks(polled...))
+	})
+
+	if _, _, err := adm.HealBytesCounter(context.Background(), 0); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+
+	counter, stop, err := adm.HealBytesCounter(context.Background(), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := polls
+		mu.Unlock()
+		if n == len(disks)-1 && counter.Load() >= 450 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Counter did not progress, got %d", counter.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	// 150 and 150 healed by the disk after its baseline, then 50
+	// after the reset, and 100 by the late disk after its baseline.
+	if n := counter.Load(); n != 450 {
+		t.Errorf("Expected 450 bytes, got %d", n)
+	}
+}
+
+// Tests the failure rate across healing disks and MRF.
+func TestFailureRate(t *testing.T) {
+	if rate := (BgHealState{}).FailureRate(); rate != 0 {
+		t.Errorf("Expected no failure rate, got %v", rate)
+	}
+
+	state := healStateWithDisks(
+		HealingDisk{ID: "disk1", ItemsHealed: 40, ItemsFailed: 10},
+		HealingDisk{ID: "disk2", ItemsHealed: 20},
+		HealingDisk{ID: "disk3", ItemsFailed: 15},
+	)
+	state.MRF = map[string]MRFStatus{"server1:9000": {ItemsHealed: 15}}
+
+	if healed := state.TotalHealed(); healed != 75 {
+		t.Errorf("Expected 75 healed items, got %d", healed)
+	}
+	if failed := state.TotalFailed(); failed != 25 {
+		t.Errorf("Expected 25 failed items, got %d", failed)
+	}
+	if rate := state.FailureRate(); rate != 0.25 {
+		t.Errorf("Expected a failure rate of 0.25, got %v", rate)
+	}
+}
+
+// Tests StuckBuckets only returns the buckets healed on no disk.
+func TestStuckBuckets(t *testing.T) {
+	state := healStateWithDisks(
+		HealingDisk{ID: "disk1", QueuedBuckets: []string{"photos", "logs"}, HealedBuckets: []string{"archive"}},
+		HealingDisk{ID: "disk2", QueuedBuckets: []string{"logs", "archive"}, HealedBuckets: []string{"photos"}},
+	)
+	if buckets := state.StuckBuckets(); !reflect.DeepEqual(buckets, []string{"logs"}) {
+		t.Errorf("Expected logs to be stuck, got %v", buckets)
+	}
+	if buckets := (BgHealState{}).StuckBuckets(); len(buckets) != 0 {
+		t.Errorf("Expected no stuck buckets, got %v", buckets)
+	}
+}
+
+// Tests MatchDisks pairs disks by ID when their indices change.
+func TestMatchDisks(t *testing.T) {
+	prev := []HealingDisk{
+		{ID: "disk1", PoolIndex: 1, DiskIndex: 0, BytesDone: 100},
+		{ID: "disk2", PoolIndex: 1, DiskIndex: 1, BytesDone: 200},
+		{ID: "disk3", PoolIndex: 1, DiskIndex: 2},
+	}
+	cur := []HealingDisk{
+		{ID: "disk2", PoolIndex: 0, DiskIndex: 0, BytesDone: 250},
+		{ID: "disk1", PoolIndex: 0, DiskIndex: 1, BytesDone: 150},
+		{ID: "disk4", PoolIndex: 0, DiskIndex: 2},
+		{PoolIndex: 0, DiskIndex: 3},
+	}
+
+	pairs := MatchDisks(prev, cur)
+	if len(pairs) != 4 {
+		t.Fatalf("Expected 4 disks, got %d", len(pairs))
+	}
+	for _, id := range []string{"disk1", "disk2"} {
+		pair := pairs[id]
+		if pair[0] == nil || pair[1] == nil || pair[0].ID != id || pair[1].ID != id {
+			t.Errorf("Expected %s to be paired, got %v", id, pair)
+			continue
+		}
+		if pair[1].BytesDone-pair[0].BytesDone != 50 {
+			t.Errorf("Expected %s to progress by 50 bytes, got %d", id, pair[1].BytesDone-pair[0].BytesDone)
+		}
+	}
+	if pair := pairs["disk3"]; pair[0] != &prev[2] || pair[1] != nil {
+		t.Errorf("Expected disk3 only in the previous snapshot, got %v", pair)
+	}
+	if pair := pairs["disk4"]; pair[0] != nil || pair[1] != &cur[2] {
+		t.Errorf("Expected disk4 only in the current snapshot, got %v", pair)
+	}
+}
+
+// Tests PermissionDeniedDrives with and without heal information.
+func TestBgHealStatePermissionDeniedDrives(t *testing.T) {
+	state := BgHealState{Sets: []SetStatus{{Disks: []Disk{
+		{State: DriveStateOk, Endpoint: "server1:9000"},
+		{State: DriveStatePermission, UUID: "uuid2", Endpoint: "server2:9000", DrivePath: "/data1", PoolIndex: 0, SetIndex: 1, DiskIndex: 2},
+		{State: DriveStatePermission, HealInfo: &HealingDisk{ID: "uuid3", Endpoint: "server3:9000", Path: "/data1"}},
+	}}}}
+
+	expected := []HealingDisk{
+		{ID: "uuid2", Endpoint: "server2:9000", Path: "/data1", SetIndex: 1, DiskIndex: 2},
+		{ID: "uuid3", Endpoint: "server3:9000", Path: "/data1"},
+	}
+	if drives := state.PermissionDeniedDrives(); !reflect.DeepEqual(drives, expected) {
+		t.Errorf("Expected %v, got %v", expected, drives)
+	}
+}
+
+// Tests DiskETA with a fixed clock.
+func TestDiskETAFixedClock(t *testing.T) {
+	started := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	disk := HealingDisk{
+		Started:          started,
+		LastUpdate:       started.Add(100 * time.Second),
+		ObjectsTotalSize: 1000,
+		BytesDone:        100,
+	}
+
+	adm := &AdminClient{}
+	now := disk.LastUpdate.Add(time.Minute)
+	adm.SetClock(func() time.Time { return now })
+	if eta := adm.DiskETA(disk); eta != 840*time.Second {
+		t.Errorf("Expected an ETA of 840s, got %v", eta)
+	}
+
+	now = disk.LastUpdate.Add(time.Hour)
+	if eta := adm.DiskETA(disk); eta != 0 {
+		t.Errorf("Expected an ETA of 0, got %v", eta)
+	}
+	if eta := adm.DiskETA(HealingDisk{}); eta != ETAUnknown {
+		t.Errorf("Expected an unknown ETA, got %v", eta)
+	}
+}
+
+// Tests CompareHealState between a healthy and a degraded cluster.
+func TestCompareHealState(t *testing.T) {
+	healthy := BgHealState{Sets: []SetStatus{{Disks: []Disk{
+		{State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOk},
+	}}}}
+	degraded := BgHealState{
+		Sets: []SetStatus{{Disks: []Disk{
+			{State: DriveStateOk}, {State: DriveStateOk}, {State: DriveStateOffline},
+			{State: DriveStateCorrupt, HealInfo: &HealingDisk{ID: "disk4", ObjectsTotalCount: 100, ItemsHealed: 30, ItemsFailed: 10}},
+		}}},
+		MRF: map[string]MRFStatus{"server1:9000": {TotalItems: 20, ItemsHealed: 10}},
+	}
+
+	c := CompareHealState(healthy, degraded)
+	expectedStates := map[DriveState][2]int{
+		DriveState(DriveStateOk): {4, 2},
+		DriveStateOffline:        {0, 1},
+		DriveStateCorrupt:        {0, 1},
+	}
+	if !reflect.DeepEqual(c.DriveStates, expectedStates) {
+		t.Errorf("Expected drive states %v, got %v", expectedStates, c.DriveStates)
+	}
+	if c.FailureRates != [2]float64{0, 0.2} {
+		t.Errorf("Expected failure rates [0 0.2], got %v", c.FailureRates)
+	}
+	if c.PendingItems != [2]uint64{0, 70} {
+		t.Errorf("Expected pending items [0 70], got %v", c.PendingItems)
+	}
+	if !c.Degraded() {
+		t.Error("Expected the second cluster to be degraded")
+	}
+
+	c = CompareHealState(healthy, healthy)
+	if len(c.DriveStates) != 0 || c.Degraded() {
+		t.Errorf("Expected no differences, got %+v", c)
+	}
+}
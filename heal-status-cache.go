@@ -0,0 +1,112 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// healStatusCall is an in-flight background heal status request,
+// shared by all the callers asking for the status meanwhile.
+type healStatusCall struct {
+	done  chan struct{}
+	state BgHealState
+	err   error
+}
+
+// defaultHealStatusFetchTimeout bounds a background heal status request
+// shared by the callers of the cache, unless the HTTP client of the
+// admin client has a timeout.
+const defaultHealStatusFetchTimeout = time.Minute
+
+// healStatusCache caches the last background heal status for ttl.
+type healStatusCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	state     BgHealState
+	fetchedAt time.Time
+	call      *healStatusCall
+}
+
+// SetStatusCacheTTL - caches the background heal status for d, calls
+// to BackgroundHealStatus made within d of the last fetch return a
+// copy of the cached status. Concurrent calls while the status is
+// being fetched share the same request to the server. A zero or
+// negative d disables the cache, which is the default.
+func (adm *AdminClient) SetStatusCacheTTL(d time.Duration) {
+	c := adm.healStatusCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = d
+	c.state = BgHealState{}
+	c.fetchedAt = time.Time{}
+}
+
+func (c *healStatusCache) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttl > 0
+}
+
+// get returns a copy of the cached status if it is still fresh
+// according to now, otherwise the status is fetched once for all the
+// concurrent callers. The fetch is not bound to the context of any
+// caller, so that a caller giving up does not fail the others, it is
+// bounded by timeout instead. Each caller waits for the result until
+// its own ctx is done. Failed fetches are not cached.
+func (c *healStatusCache) get(ctx context.Context, now func() time.Time, timeout time.Duration,
+	fetch func(context.Context) (BgHealState, error)) (BgHealState, error) {
+
+	c.mu.Lock()
+	if !c.fetchedAt.IsZero() && now().Sub(c.fetchedAt) < c.ttl {
+		state := c.state.Clone()
+		c.mu.Unlock()
+		return state, nil
+	}
+	call := c.call
+	if call == nil {
+		call = &healStatusCall{done: make(chan struct{})}
+		c.call = call
+		go func() {
+			fetchCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			state, err := fetch(fetchCtx)
+			cancel()
+
+			c.mu.Lock()
+			call.state, call.err = state, err
+			if err == nil {
+				c.state, c.fetchedAt = state, now()
+			}
+			c.call = nil
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return BgHealState{}, ctx.Err()
+	case <-call.done:
+	}
+	if call.err != nil {
+		return BgHealState{}, call.err
+	}
+	return call.state.Clone(), nil
+}
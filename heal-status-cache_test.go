@@ -0,0 +1,114 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests concurrent BackgroundHealStatus calls share a single request
+// and receive copies of the status.
+func TestStatusCacheTTL(t *testing.T) {
+	var requests int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Leave time for all the callers to join the request.
+		time.Sleep(100 * time.Millisecond)
+		json.NewEncoder(w).Encode(healStateWithDisks(HealingDisk{ID: "disk1", QueuedBuckets: []string{"bucket"}}))
+	})
+	adm.SetStatusCacheTTL(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state, err := adm.BackgroundHealStatus(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// Callers own their copy.
+			state.Sets[0].Disks[0].HealInfo.QueuedBuckets[0] = "modified"
+		}()
+	}
+	wg.Wait()
+
+	state, err := adm.BackgroundHealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Sets[0].Disks[0].HealInfo.QueuedBuckets[0]; got != "bucket" {
+		t.Errorf("Expected cached status to be unmodified, got %q", got)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", n)
+	}
+
+	adm.SetStatusCacheTTL(0)
+	if _, err = adm.BackgroundHealStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected disabled cache to reach the server, got %d requests", n)
+	}
+}
+
+// Tests a caller canceling its context does not fail the other callers
+// sharing the request, and the status is still cached.
+func TestStatusCacheCallerCanceled(t *testing.T) {
+	var requests int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		json.NewEncoder(w).Encode(healStateWithDisks(HealingDisk{ID: "disk1"}))
+	})
+	adm.SetStatusCacheTTL(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := adm.BackgroundHealStatus(ctx)
+		errCh <- err
+	}()
+	// Join the request started by the canceled caller.
+	time.Sleep(10 * time.Millisecond)
+	state, err := adm.BackgroundHealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Sets) != 1 {
+		t.Errorf("Unexpected status %+v", state)
+	}
+	if err = <-errCh; err != context.DeadlineExceeded {
+		t.Errorf("Expected %v for the canceled caller, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, err = adm.BackgroundHealStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", n)
+	}
+}
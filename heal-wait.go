@@ -0,0 +1,438 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// minHealPollTimeout is the minimum time given to a single heal
+// status request made by HealWait and HealStream.
+const minHealPollTimeout = time.Minute
+
+// HealWaitOption configures HealWait and HealStream.
+type HealWaitOption func(*healWaitOptions)
+
+type healWaitOptions struct {
+	pollTimeout      time.Duration
+	onPoll           func(HealTaskStatus)
+	afterResultIndex *int64
+	failuresOnly     bool
+	onTransition     func(from, to HealSummaryState)
+}
+
+// WithPollTimeout bounds each heal status request to d, including
+// its retries. By default a request is given four times the poll
+// interval, and at least a minute.
+func WithPollTimeout(d time.Duration) HealWaitOption {
+	return func(o *healWaitOptions) {
+		o.pollTimeout = d
+	}
+}
+
+// WithOnPoll calls fn with the status returned by each poll, before
+// its items are processed. fn is called from the poll loop and must
+// return quickly, a slow callback delays the following polls.
+func WithOnPoll(fn func(HealTaskStatus)) HealWaitOption {
+	return func(o *healWaitOptions) {
+		o.onPoll = fn
+	}
+}
+
+// WithAfterResultIndex makes HealStream skip the items with a
+// ResultIndex lower or equal to index, e.g. to resume consuming a heal
+// sequence after a restart without processing its items again. Result
+// indices increase monotonically within a sequence but restart when
+// the server restarts the sequence, in which case the items of the new
+// run up to index are skipped as well.
+func WithAfterResultIndex(index int64) HealWaitOption {
+	return func(o *healWaitOptions) {
+		o.afterResultIndex = &index
+	}
+}
+
+// WithFailuresOnly makes HealStream only send the items which failed
+// to heal, i.e. with a Detail or with data loss, see
+// HealResultItem.DataLoss.
+func WithFailuresOnly() HealWaitOption {
+	return func(o *healWaitOptions) {
+		o.failuresOnly = true
+	}
+}
+
+// WithOnTransition calls fn each time the state of the heal sequence
+// changes between polls, e.g. from HealRunningState to
+// HealFinishedState. Polls reporting the same state as the previous
+// one don't call fn. The state before the first poll is
+// HealNotStartedState. Like WithOnPoll, fn must return quickly.
+func WithOnTransition(fn func(from, to HealSummaryState)) HealWaitOption {
+	return func(o *healWaitOptions) {
+		o.onTransition = fn
+	}
+}
+
+func newHealWaitOptions(pollInterval time.Duration, opts []HealWaitOption) healWaitOptions {
+	var o healWaitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.pollTimeout <= 0 {
+		o.pollTimeout = 4 * pollInterval
+		if o.pollTimeout < minHealPollTimeout {
+			o.pollTimeout = minHealPollTimeout
+		}
+	}
+	return o
+}
+
+// healEnded returns true if the heal sequence is not running anymore.
+func healEnded(status HealTaskStatus) bool {
+	switch status.State() {
+	case HealFinishedState, HealStoppedState:
+		return true
+	}
+	return false
+}
+
+// pollHeal polls the status of the heal sequence every pollInterval
+// and calls fn with each status until the sequence ends, ctx is
+// canceled or fn returns an error. The last status is returned. A
+// poll interval which is not positive is rejected. When the polling
+// ends because ctx is done, the heal sequence is force stopped so it
+// isn't left running on the server.
+func (adm *AdminClient) pollHeal(ctx context.Context, bucket, prefix, clientToken string,
+	pollInterval time.Duration, o healWaitOptions, fn func(HealTaskStatus) error) (last HealTaskStatus, err error) {
+
+	if pollInterval <= 0 {
+		return HealTaskStatus{}, ErrInvalidArgument("heal poll interval must be positive")
+	}
+	defer func() {
+		if err != nil {
+			adm.stopHealOnDone(ctx, bucket, prefix, HealOpts{})
+		}
+	}()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	state := HealNotStartedState
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, o.pollTimeout)
+		status, err := adm.HealStatus(pollCtx, bucket, prefix, clientToken)
+		cancel()
+		if err != nil {
+			return status, err
+		}
+		if o.onPoll != nil {
+			o.onPoll(status)
+		}
+		if status.State() != state {
+			if o.onTransition != nil {
+				o.onTransition(state, status.State())
+			}
+			state = status.State()
+		}
+		if err = fn(status); err != nil {
+			return status, err
+		}
+		if healEnded(status) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// HealWait - polls the heal sequence identified by clientToken every
+// pollInterval until it ends and returns its final status, with the
+// items reported across all the polls.
+//
+// Each poll is bounded by its own timeout (see WithPollTimeout)
+// independently of the lifetime of the whole wait, which is only
+// bounded by ctx. A deadline on ctx applies to every poll as well.
+// When ctx is canceled or its deadline expires before the sequence
+// ends, the sequence is force stopped with a separate short lived
+// context and the context error is returned. A poll interval which is
+// not positive is rejected with an ErrInvalidArgument error.
+func (adm *AdminClient) HealWait(ctx context.Context, bucket, prefix, clientToken string,
+	pollInterval time.Duration, opts ...HealWaitOption) (HealTaskStatus, error) {
+
+	o := newHealWaitOptions(pollInterval, opts)
+
+	var items []HealResultItem
+	status, err := adm.pollHeal(ctx, bucket, prefix, clientToken, pollInterval, o, func(status HealTaskStatus) error {
+		items = append(items, status.Items...)
+		return nil
+	})
+	status.Items = items
+	return status, err
+}
+
+// HealStream - polls the heal sequence identified by clientToken every
+// pollInterval until it ends and sends each reported item on the
+// returned channel. The item channel is closed when the sequence
+// ends, after which the error channel receives any error before being
+// closed. Polls are bounded as described in HealWait. Items already
+// consumed can be skipped with WithAfterResultIndex, and successful
+// items with WithFailuresOnly. Once ctx is done the sequence is force
+// stopped on the server and the context error is reported. A poll
+// interval which is not positive is reported on the error channel.
+func (adm *AdminClient) HealStream(ctx context.Context, bucket, prefix, clientToken string,
+	pollInterval time.Duration, opts ...HealWaitOption) (<-chan HealResultItem, <-chan error) {
+
+	o := newHealWaitOptions(pollInterval, opts)
+
+	itemCh := make(chan HealResultItem)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		_, err := adm.pollHeal(ctx, bucket, prefix, clientToken, pollInterval, o, func(status HealTaskStatus) error {
+			for _, item := range status.Items {
+				if o.afterResultIndex != nil && item.ResultIndex <= *o.afterResultIndex {
+					continue
+				}
+				if o.failuresOnly && item.Detail == "" && !item.DataLoss() {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case itemCh <- item:
+				}
+			}
+			return nil
+		})
+		close(itemCh)
+		if err != nil {
+			errCh <- err
+		}
+	}()
+	return itemCh, errCh
+}
+
+// healStopTimeout bounds the force stop of a heal sequence issued once
+// the context of its caller is done.
+const healStopTimeout = 10 * time.Second
+
+// stopHealOnDone force stops the heal sequence on bucket/prefix if ctx
+// is done, so that the sequence is not left running on the server. ctx
+// can't be used for the request anymore, a fresh context bounded by
+// healStopTimeout is used instead. Errors are ignored, the caller
+// reports the context error.
+func (adm *AdminClient) stopHealOnDone(ctx context.Context, bucket, prefix string, opts HealOpts) {
+	if ctx.Err() == nil {
+		return
+	}
+	adm.stopHeal(bucket, prefix, opts)
+}
+
+// stopHeal force stops the heal sequence on bucket/prefix with a fresh
+// context bounded by healStopTimeout. Errors are ignored.
+func (adm *AdminClient) stopHeal(bucket, prefix string, opts HealOpts) {
+	stopCtx, cancel := context.WithTimeout(context.Background(), healStopTimeout)
+	defer cancel()
+	adm.Heal(stopCtx, bucket, prefix, opts, "", false, true)
+}
+
+// healInspectPollInterval is the interval at which HealInspect polls
+// the status of its dry-run heal.
+const healInspectPollInterval = 100 * time.Millisecond
+
+// HealInspect - returns the current state of the drives of an object,
+// optionally of a specific version, without healing it. A non recursive
+// dry-run heal of the object is run and its result item returned.
+//
+// When ctx is canceled or its deadline expires while the heal is
+// running, the heal sequence is force stopped with a separate short
+// lived context, so it is not orphaned on the server.
+func (adm *AdminClient) HealInspect(ctx context.Context, bucket, object, versionID string) (HealResultItem, error) {
+	return adm.healObject(ctx, bucket, object, versionID, HealOpts{DryRun: true})
+}
+
+// ErrHealNotVerified is returned by HealAndVerify when the object is
+// still not fully redundant after being healed.
+var ErrHealNotVerified = errors.New("object is not fully redundant after heal")
+
+// HealAndVerify - heals an object, optionally a specific version, then
+// inspects it with a deep scan dry-run heal to confirm the heal. Both
+// result items are returned. ErrHealNotVerified is returned when the
+// inspection still reports a drive which is not healthy, data loss or
+// an error detail. If ctx is canceled or expires during either heal,
+// that heal is force stopped on the server with a separate short lived
+// context and the context error is returned, the inspection is not
+// started after a canceled heal.
+func (adm *AdminClient) HealAndVerify(ctx context.Context, bucket, object, versionID string) (healed HealResultItem, verify HealResultItem, err error) {
+	healed, err = adm.healObject(ctx, bucket, object, versionID, HealOpts{})
+	if err != nil {
+		return healed, verify, err
+	}
+	verify, err = adm.healObject(ctx, bucket, object, versionID, HealOpts{DryRun: true, ScanMode: HealDeepScan})
+	if err != nil {
+		return healed, verify, err
+	}
+	if verify.Detail != "" || verify.DataLoss() || !verify.fullyRedundant() {
+		return healed, verify, ErrHealNotVerified
+	}
+	return healed, verify, nil
+}
+
+// healObject runs a non recursive heal of the object with opts and
+// returns its result item.
+func (adm *AdminClient) healObject(ctx context.Context, bucket, object, versionID string, opts HealOpts) (HealResultItem, error) {
+	opts.VersionID = versionID
+	healStart, _, err := adm.Heal(ctx, bucket, object, opts, "", false, false)
+	if err != nil {
+		return HealResultItem{}, err
+	}
+	status, err := adm.HealWait(ctx, bucket, object, healStart.ClientToken, healInspectPollInterval)
+	if err != nil {
+		return HealResultItem{}, err
+	}
+	for _, item := range status.Items {
+		if item.Type != HealItemObject || item.Bucket != bucket || item.Object != object {
+			continue
+		}
+		if versionID != "" && item.VersionID != versionID {
+			continue
+		}
+		return item, nil
+	}
+	return HealResultItem{}, ErrUnexpectedResponse("No heal result returned for " + bucket + "/" + object)
+}
+
+// EstimateDriveHealImpact - returns the number of objects, and their
+// total size, which would need to be rebuilt if the drive at endpoint
+// was replaced. A recursive dry-run heal of the whole cluster targeting
+// the drive is run, nothing is modified. Objects are counted as they
+// are reported, without keeping the items in memory, when one of their
+// drives is at endpoint, so that servers which don't support targeting
+// a drive give the same result. Endpoints are compared regardless of
+// the case of their scheme and host and of trailing slashes. A drive
+// path without a host is rejected once the server reports drives by
+// URL, as it can't be told apart among the servers. When ctx is
+// canceled or its deadline expires, the heal is force stopped with a
+// separate short lived context so it is not left running on the
+// server.
+func (adm *AdminClient) EstimateDriveHealImpact(ctx context.Context, endpoint string) (objects uint64, bytes uint64, err error) {
+	opts := HealOpts{Recursive: true, DryRun: true, Endpoint: endpoint}
+	healStart, _, err := adm.Heal(ctx, "", "", opts, "", false, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	target := normalizeDriveEndpoint(endpoint)
+	isPath := strings.HasPrefix(endpoint, "/")
+	o := newHealWaitOptions(healInspectPollInterval, nil)
+	_, err = adm.pollHeal(ctx, "", "", healStart.ClientToken, healInspectPollInterval, o, func(status HealTaskStatus) error {
+		for _, item := range status.Items {
+			if item.Type != HealItemObject {
+				continue
+			}
+			onDrive := false
+			for _, d := range item.Before.Drives {
+				if isPath && !strings.HasPrefix(d.Endpoint, "/") {
+					return ErrInvalidArgument("drive endpoint must be a URL, the server reports drives by URL: " + endpoint)
+				}
+				if normalizeDriveEndpoint(d.Endpoint) == target {
+					onDrive = true
+				}
+			}
+			if !onDrive {
+				continue
+			}
+			objects++
+			if item.ObjectSize > 0 {
+				bytes += uint64(item.ObjectSize)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The heal may still be running, e.g. when the endpoint was
+		// rejected, pollHeal already stopped it if ctx is done.
+		if ctx.Err() == nil {
+			adm.stopHeal("", "", opts)
+		}
+		return 0, 0, err
+	}
+	return objects, bytes, nil
+}
+
+// normalizeDriveEndpoint returns the drive endpoint with a lower case
+// scheme and host and a clean path, for comparisons.
+func normalizeDriveEndpoint(endpoint string) string {
+	if strings.HasPrefix(endpoint, "/") {
+		return path.Clean(endpoint)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + path.Clean("/"+u.Path)
+}
+
+// healCollectPollInterval is the interval at which HealCollect polls
+// the status of its heal.
+var healCollectPollInterval = time.Second
+
+// HealCollect - starts a heal sequence on bucket/prefix and polls it
+// until it ends, collecting the items reported across the polls. To
+// keep memory bounded, every maxItems collected items are passed to
+// onOverflow, which may retain them, and dropped from the collection.
+// Items are dropped without being passed anywhere when onOverflow is
+// nil. The final status holds the items not passed to onOverflow yet.
+// When ctx is done before the heal ends, the heal is force stopped
+// with its own short lived context, so it doesn't keep running on the
+// server, and the items collected so far are returned with the context
+// error.
+func (adm *AdminClient) HealCollect(ctx context.Context, bucket, prefix string, opts HealOpts,
+	maxItems int, onOverflow func([]HealResultItem)) (HealTaskStatus, error) {
+
+	if maxItems <= 0 {
+		return HealTaskStatus{}, ErrInvalidArgument("heal collect max items must be positive")
+	}
+	healStart, _, err := adm.Heal(ctx, bucket, prefix, opts, "", false, false)
+	if err != nil {
+		return HealTaskStatus{}, err
+	}
+
+	o := newHealWaitOptions(healCollectPollInterval, nil)
+	items := make([]HealResultItem, 0, maxItems)
+	status, err := adm.pollHeal(ctx, bucket, prefix, healStart.ClientToken, healCollectPollInterval, o, func(status HealTaskStatus) error {
+		for _, item := range status.Items {
+			items = append(items, item)
+			if len(items) < maxItems {
+				continue
+			}
+			if onOverflow != nil {
+				onOverflow(items)
+			}
+			items = make([]HealResultItem, 0, maxItems)
+		}
+		return nil
+	})
+	status.Items = items
+	return status, err
+}
@@ -0,0 +1,463 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHealStatusServer returns a client whose heal status requests are
+// served, in order, from statuses. The last status is repeated.
+func newHealStatusServer(t *testing.T, delay time.Duration, statuses ...HealTaskStatus) *AdminClient {
+	var (
+		mu   sync.Mutex
+		poll int
+	)
+	return newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		mu.Lock()
+		status := statuses[poll]
+		if poll < len(statuses)-1 {
+			poll++
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// Tests that a slow poll within the poll timeout is not canceled.
+func TestHealWaitSlowPoll(t *testing.T) {
+	adm := newHealStatusServer(t, 200*time.Millisecond,
+		HealTaskStatus{Summary: string(HealRunningState), Items: []HealResultItem{{ResultIndex: 1}}},
+		HealTaskStatus{Summary: string(HealFinishedState), Items: []HealResultItem{{ResultIndex: 2}}},
+	)
+
+	status, err := adm.HealWait(context.Background(), "bucket", "", "token", 10*time.Millisecond, WithPollTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State() != HealFinishedState {
+		t.Errorf("Expected %q, got %q", HealFinishedState, status.State())
+	}
+	if len(status.Items) != 2 {
+		t.Errorf("Expected 2 items, got %d", len(status.Items))
+	}
+}
+
+// Tests a zero poll interval is rejected instead of panicking.
+func TestHealWaitZeroPollInterval(t *testing.T) {
+	adm := newHealStatusServer(t, 0, HealTaskStatus{Summary: string(HealRunningState)})
+
+	if _, err := adm.HealWait(context.Background(), "bucket", "", "token", 0); err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+
+	itemCh, errCh := adm.HealStream(context.Background(), "bucket", "", "token", 0)
+	for range itemCh {
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected error for a zero poll interval")
+	}
+}
+
+// Tests the poll callback is invoked once per poll.
+func TestHealStreamOnPoll(t *testing.T) {
+	adm := newHealStatusServer(t, 0,
+		HealTaskStatus{Summary: string(HealRunningState), Items: []HealResultItem{{ResultIndex: 1}}},
+		HealTaskStatus{Summary: string(HealRunningState)},
+		HealTaskStatus{Summary: string(HealFinishedState), Items: []HealResultItem{{ResultIndex: 2}}},
+	)
+
+	var polls int
+	itemCh, errCh := adm.HealStream(context.Background(), "bucket", "", "token", time.Millisecond,
+		WithOnPoll(func(HealTaskStatus) { polls++ }))
+	var items int
+	for range itemCh {
+		items++
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls, got %d", polls)
+	}
+	if items != 2 {
+		t.Errorf("Expected 2 items, got %d", items)
+	}
+}
+
+// Tests the transition callback fires once per state change.
+func TestHealWaitOnTransition(t *testing.T) {
+	adm := newHealStatusServer(t, 0,
+		HealTaskStatus{Summary: string(HealNotStartedState)},
+		HealTaskStatus{Summary: string(HealRunningState)},
+		HealTaskStatus{Summary: string(HealRunningState)},
+		HealTaskStatus{Summary: string(HealRunningState)},
+		HealTaskStatus{Summary: string(HealFinishedState)},
+	)
+
+	var transitions [][2]HealSummaryState
+	_, err := adm.HealWait(context.Background(), "bucket", "", "token", time.Millisecond,
+		WithOnTransition(func(from, to HealSummaryState) {
+			transitions = append(transitions, [2]HealSummaryState{from, to})
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]HealSummaryState{
+		{HealNotStartedState, HealRunningState},
+		{HealRunningState, HealFinishedState},
+	}
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("Expected %v, got %v", expected, transitions)
+	}
+}
+
+// Tests HealStream skips the items up to the resumed result index.
+func TestHealStreamAfterResultIndex(t *testing.T) {
+	adm := newHealStatusServer(t, 0,
+		HealTaskStatus{Summary: string(HealRunningState), Items: []HealResultItem{{ResultIndex: 1}, {ResultIndex: 2}}},
+		HealTaskStatus{Summary: string(HealFinishedState), Items: []HealResultItem{{ResultIndex: 3}, {ResultIndex: 4}}},
+	)
+
+	itemCh, errCh := adm.HealStream(context.Background(), "bucket", "", "token", time.Millisecond,
+		WithAfterResultIndex(2))
+	var indices []int64
+	for item := range itemCh {
+		indices = append(indices, item.ResultIndex)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, []int64{3, 4}) {
+		t.Errorf("Expected items 3 and 4, got %v", indices)
+	}
+}
+
+// Tests HealStream only sends failed items when asked to.
+func TestHealStreamFailuresOnly(t *testing.T) {
+	lost := HealResultItem{ResultIndex: 3, Type: HealItemObject, DataBlocks: 2}
+	lost.After.Drives = []HealDriveInfo{{State: DriveStateOk}, {State: DriveStateMissing}}
+	adm := newHealStatusServer(t, 0,
+		HealTaskStatus{Summary: string(HealRunningState), Items: []HealResultItem{{ResultIndex: 1}, {ResultIndex: 2, Detail: "file is corrupted"}}},
+		HealTaskStatus{Summary: string(HealFinishedState), Items: []HealResultItem{lost, {ResultIndex: 4}}},
+	)
+
+	itemCh, errCh := adm.HealStream(context.Background(), "bucket", "", "token", time.Millisecond,
+		WithFailuresOnly())
+	var indices []int64
+	for item := range itemCh {
+		indices = append(indices, item.ResultIndex)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, []int64{2, 3}) {
+		t.Errorf("Expected items 2 and 3, got %v", indices)
+	}
+}
+
+// Tests HealInspect runs a non recursive dry-run and returns the item.
+func TestHealInspect(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("clientToken") != "" {
+			json.NewEncoder(w).Encode(HealTaskStatus{
+				Summary: string(HealFinishedState),
+				Items: []HealResultItem{
+					{Type: HealItemBucket, Bucket: "bucket"},
+					{Type: HealItemObject, Bucket: "bucket", Object: "object", VersionID: "v1", DataBlocks: 2},
+				},
+			})
+			return
+		}
+		var opts HealOpts
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			t.Error(err)
+		}
+		if !opts.DryRun || opts.Recursive {
+			t.Errorf("Expected non recursive dry-run, got %+v", opts)
+		}
+		if r.URL.Query().Get("versionId") != "v1" {
+			t.Errorf("Expected version v1, got %q", r.URL.Query().Get("versionId"))
+		}
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+	})
+
+	item, err := adm.HealInspect(context.Background(), "bucket", "object", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Object != "object" || item.DataBlocks != 2 {
+		t.Errorf("Unexpected item %+v", item)
+	}
+}
+
+// Tests HealInspect force stops the heal once its context expires.
+func TestHealInspectStopOnDone(t *testing.T) {
+	var stopped int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("forceStop") == "true":
+			atomic.StoreInt32(&stopped, 1)
+			json.NewEncoder(w).Encode(HealStopSuccess{ClientToken: "token"})
+		case r.URL.Query().Get("clientToken") != "":
+			json.NewEncoder(w).Encode(HealTaskStatus{Summary: string(HealRunningState)})
+		default:
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if _, err := adm.HealInspect(ctx, "bucket", "object", ""); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if atomic.LoadInt32(&stopped) != 1 {
+		t.Error("Expected the heal to be force stopped")
+	}
+}
+
+// Tests EstimateDriveHealImpact runs a drive-targeted dry-run and
+// aggregates the objects on the drive.
+func TestEstimateDriveHealImpact(t *testing.T) {
+	object := func(name string, size int64, endpoints ...string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: name, ObjectSize: size}
+		for _, e := range endpoints {
+			item.Before.Drives = append(item.Before.Drives, HealDriveInfo{Endpoint: e, State: DriveStateOk})
+		}
+		return item
+	}
+	newServer := func(drive1, drive2 string) *AdminClient {
+		return newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			switch {
+			case query.Get("forceStop") == "true":
+				json.NewEncoder(w).Encode(HealStopSuccess{})
+				return
+			case query.Get("clientToken") != "":
+				json.NewEncoder(w).Encode(HealTaskStatus{
+					Summary: string(HealFinishedState),
+					Items: []HealResultItem{
+						{Type: HealItemBucket, Bucket: "bucket"},
+						object("a", 100, drive1, drive2),
+						object("b", 50, drive2, drive1),
+						object("c", 1000, drive2),
+					},
+				})
+				return
+			}
+			if query.Get("endpoint") == "" {
+				t.Error("Expected an endpoint")
+			}
+			var opts HealOpts
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				t.Error(err)
+			}
+			if !opts.DryRun || !opts.Recursive {
+				t.Errorf("Expected recursive dry-run, got %+v", opts)
+			}
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+		})
+	}
+
+	testCases := []struct {
+		drive1, drive2 string
+		endpoint       string
+		objects, bytes uint64
+		err            bool
+	}{
+		{drive1: "http://server1:9000/data1", drive2: "http://server2:9000/data1", endpoint: "http://server1:9000/data1", objects: 2, bytes: 150},
+		{drive1: "http://server1:9000/data1", drive2: "http://server2:9000/data1", endpoint: "HTTP://Server1:9000/data1/", objects: 2, bytes: 150},
+		{drive1: "/data1", drive2: "/data2", endpoint: "/data1/", objects: 2, bytes: 150},
+		{drive1: "http://server1:9000/data1", drive2: "http://server2:9000/data1", endpoint: "/data1", err: true},
+	}
+	for i, testCase := range testCases {
+		adm := newServer(testCase.drive1, testCase.drive2)
+		objects, bytes, err := adm.EstimateDriveHealImpact(context.Background(), testCase.endpoint)
+		if testCase.err {
+			if err == nil {
+				t.Errorf("Test %d: Expected error", i+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		if objects != testCase.objects || bytes != testCase.bytes {
+			t.Errorf("Test %d: Expected %d objects of %d bytes, got %d objects of %d bytes",
+				i+1, testCase.objects, testCase.bytes, objects, bytes)
+		}
+	}
+}
+
+// Tests HealAndVerify reports an object still degraded after the heal.
+func TestHealAndVerify(t *testing.T) {
+	object := func(states ...string) HealResultItem {
+		item := HealResultItem{Type: HealItemObject, Bucket: "bucket", Object: "object", DataBlocks: 2}
+		for _, state := range states {
+			item.After.Drives = append(item.After.Drives, HealDriveInfo{State: state})
+		}
+		return item
+	}
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("clientToken") {
+		case "heal":
+			json.NewEncoder(w).Encode(HealTaskStatus{
+				Summary: string(HealFinishedState),
+				Items:   []HealResultItem{object(DriveStateOk, DriveStateOk, DriveStateOk)},
+			})
+			return
+		case "verify":
+			json.NewEncoder(w).Encode(HealTaskStatus{
+				Summary: string(HealFinishedState),
+				Items:   []HealResultItem{object(DriveStateOk, DriveStateCorrupt, DriveStateOk)},
+			})
+			return
+		}
+		var opts HealOpts
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			t.Error(err)
+		}
+		if !opts.DryRun {
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "heal"})
+			return
+		}
+		if opts.ScanMode != HealDeepScan {
+			t.Errorf("Expected a deep scan verification, got %v", opts.ScanMode)
+		}
+		json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "verify"})
+	})
+
+	healed, verify, err := adm.HealAndVerify(context.Background(), "bucket", "object", "")
+	if err != ErrHealNotVerified {
+		t.Fatalf("Expected %v, got %v", ErrHealNotVerified, err)
+	}
+	if !healed.fullyRedundant() {
+		t.Errorf("Expected the healed object to be fully redundant, got %+v", healed)
+	}
+	if verify.After.Drives[1].State != DriveStateCorrupt {
+		t.Errorf("Expected the verification to report the corrupt drive, got %+v", verify)
+	}
+}
+
+// Tests HealCollect passes the items to the overflow callback in
+// batches of at most maxItems.
+func TestHealCollect(t *testing.T) {
+	defer func(interval time.Duration) {
+		healCollectPollInterval = interval
+	}(healCollectPollInterval)
+	healCollectPollInterval = time.Millisecond
+
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("clientToken") == "" {
+			json.NewEncoder(w).Encode(HealStartSuccess{ClientToken: "token"})
+			return
+		}
+		mu.Lock()
+		polls++
+		status := HealTaskStatus{Summary: string(HealRunningState)}
+		for i := 1; i <= 3; i++ {
+			status.Items = append(status.Items, HealResultItem{ResultIndex: int64((polls-1)*3 + i)})
+		}
+		if polls == 3 {
+			status.Summary = string(HealFinishedState)
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(status)
+	})
+
+	var batches [][]int64
+	status, err := adm.HealCollect(context.Background(), "bucket", "", HealOpts{Recursive: true}, 4, func(items []HealResultItem) {
+		var batch []int64
+		for _, item := range items {
+			batch = append(batch, item.ResultIndex)
+		}
+		batches = append(batches, batch)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]int64{{1, 2, 3, 4}, {5, 6, 7, 8}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("Expected batches %v, got %v", expected, batches)
+	}
+	if status.State() != HealFinishedState || len(status.Items) != 1 || status.Items[0].ResultIndex != 9 {
+		t.Errorf("Unexpected final status %+v", status)
+	}
+
+	if _, err = adm.HealCollect(context.Background(), "bucket", "", HealOpts{}, 0, nil); err == nil {
+		t.Error("Expected error for zero max items")
+	}
+}
+
+// newHealStopServer returns a client of a server running a heal which
+// never ends, the returned function reports whether it was stopped.
+func newHealStopServer(t *testing.T) (*AdminClient, func() bool) {
+	var stopped int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("forceStop") == "true":
+			atomic.StoreInt32(&stopped, 1)
+			json.NewEncoder(w).Encode(HealStopSuccess{ClientToken: "token"})
+		default:
+			json.NewEncoder(w).Encode(HealTaskStatus{
+				Summary: string(HealRunningState),
+				Items:   []HealResultItem{{ResultIndex: 1}},
+			})
+		}
+	})
+	return adm, func() bool { return atomic.LoadInt32(&stopped) == 1 }
+}
+
+// Tests HealWait and HealStream force stop the heal once their context
+// is canceled.
+func TestHealWaitStopOnDone(t *testing.T) {
+	adm, stopped := newHealStopServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := adm.HealWait(ctx, "bucket", "", "token", 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if !stopped() {
+		t.Error("Expected HealWait to force stop the heal")
+	}
+
+	adm, stopped = newHealStopServer(t)
+	ctx, cancel = context.WithCancel(context.Background())
+	itemCh, errCh := adm.HealStream(ctx, "bucket", "", "token", 10*time.Millisecond)
+	<-itemCh
+	cancel()
+	for range itemCh {
+	}
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	if !stopped() {
+		t.Error("Expected HealStream to force stop the heal")
+	}
+}
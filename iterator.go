@@ -0,0 +1,94 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import "context"
+
+// pageFetcher fetches the page of items starting after marker and
+// returns the marker of the next page, empty when it is the last one.
+type pageFetcher func(ctx context.Context, marker string) (items []interface{}, nextMarker string, err error)
+
+// pageIterator iterates over the items of a paginated list API,
+// it is wrapped by typed iterators such as HealSequenceIterator.
+type pageIterator struct {
+	fetch  pageFetcher
+	page   []interface{}
+	marker string
+	last   bool
+	err    error
+}
+
+// next returns the next item, false is returned once all the items
+// have been returned or an error occurred.
+func (it *pageIterator) next(ctx context.Context) (interface{}, bool, error) {
+	for len(it.page) == 0 {
+		if it.err != nil || it.last {
+			return nil, false, it.err
+		}
+		it.page, it.marker, it.err = it.fetch(ctx, it.marker)
+		if it.err != nil {
+			return nil, false, it.err
+		}
+		it.last = it.marker == ""
+	}
+	item := it.page[0]
+	it.page = it.page[1:]
+	return item, true, nil
+}
+
+// healSequencesPageSize is the number of heal sequences fetched per
+// page by HealSequenceIterator.
+const healSequencesPageSize = 1000
+
+// HealSequenceIterator - iterates over the heal sequences known to the
+// server, fetching them one page at a time.
+type HealSequenceIterator struct {
+	it pageIterator
+}
+
+// Next returns the next heal sequence. false is returned once all the
+// sequences have been returned or when an error occurred.
+func (i *HealSequenceIterator) Next(ctx context.Context) (HealSequenceInfo, bool, error) {
+	item, ok, err := i.it.next(ctx)
+	if !ok {
+		return HealSequenceInfo{}, false, err
+	}
+	return item.(HealSequenceInfo), true, nil
+}
+
+// HealSequenceIterator returns an iterator over the heal sequences
+// known to the server. No request is made until Next is called, pages
+// are fetched with the context passed to Next.
+func (adm *AdminClient) HealSequenceIterator() *HealSequenceIterator {
+	return &HealSequenceIterator{it: pageIterator{
+		fetch: func(ctx context.Context, marker string) ([]interface{}, string, error) {
+			sequences, err := adm.listHealSequencesPage(ctx, marker, healSequencesPageSize)
+			if err != nil {
+				return nil, "", err
+			}
+			items := make([]interface{}, 0, len(sequences))
+			for _, seq := range sequences {
+				items = append(items, seq)
+			}
+			var nextMarker string
+			if len(sequences) == healSequencesPageSize {
+				nextMarker = sequences[len(sequences)-1].ClientToken
+			}
+			return items, nextMarker, nil
+		},
+	}}
+}
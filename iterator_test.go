@@ -0,0 +1,92 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Tests HealSequenceIterator over two pages.
+func TestHealSequenceIterator(t *testing.T) {
+	pages := map[string][]interface{}{
+		"":  {HealSequenceInfo{ClientToken: "a"}, HealSequenceInfo{ClientToken: "b"}},
+		"b": {HealSequenceInfo{ClientToken: "c"}},
+	}
+	var markers []string
+	it := &HealSequenceIterator{it: pageIterator{
+		fetch: func(ctx context.Context, marker string) ([]interface{}, string, error) {
+			markers = append(markers, marker)
+			if marker == "" {
+				return pages[marker], "b", nil
+			}
+			return pages[marker], "", nil
+		},
+	}}
+
+	var tokens []string
+	for {
+		seq, ok, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		tokens = append(tokens, seq.ClientToken)
+	}
+	if !reflect.DeepEqual(tokens, []string{"a", "b", "c"}) {
+		t.Errorf("Unexpected sequences %v", tokens)
+	}
+	if !reflect.DeepEqual(markers, []string{"", "b"}) {
+		t.Errorf("Unexpected page markers %v", markers)
+	}
+	if _, ok, _ := it.Next(context.Background()); ok {
+		t.Error("Expected exhausted iterator")
+	}
+}
+
+// Tests HealSequenceIterator fetches the sequences of the server.
+func TestAdminHealSequenceIterator(t *testing.T) {
+	var requests int
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode([]HealSequenceInfo{{ClientToken: "a"}, {ClientToken: "b"}})
+	})
+
+	it := adm.HealSequenceIterator()
+	if requests != 0 {
+		t.Fatalf("Expected no request before Next, got %d", requests)
+	}
+	var tokens []string
+	for {
+		seq, ok, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		tokens = append(tokens, seq.ClientToken)
+	}
+	if !reflect.DeepEqual(tokens, []string{"a", "b"}) || requests != 1 {
+		t.Errorf("Unexpected sequences %v after %d requests", tokens, requests)
+	}
+}
@@ -0,0 +1,121 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultiError - holds the errors of a set of operations executed
+// together, keyed by the name of the operation (e.g. the prefix
+// being healed).
+type MultiError struct {
+	Errors map[string]error
+
+	mu sync.Mutex
+}
+
+// Add - records err for key, nil errors are ignored. Safe for
+// concurrent use.
+func (m *MultiError) Add(key string, err error) {
+	if err == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Errors == nil {
+		m.Errors = make(map[string]error)
+	}
+	m.Errors[key] = err
+}
+
+// Any - returns true if at least one operation failed.
+func (m *MultiError) Any() bool {
+	return m != nil && len(m.Errors) > 0
+}
+
+// ErrorOrNil - returns m if any operation failed, nil otherwise.
+func (m *MultiError) ErrorOrNil() error {
+	if !m.Any() {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) keys() []string {
+	keys := make([]string, 0, len(m.Errors))
+	for k := range m.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Error - returns a summary of all the errors, sorted by key.
+func (m *MultiError) Error() string {
+	if !m.Any() {
+		return "no errors"
+	}
+	keys := m.keys()
+	msgs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", k, m.Errors[k]))
+	}
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d operations failed: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap - returns the individual errors, sorted by key.
+func (m *MultiError) Unwrap() []error {
+	if !m.Any() {
+		return nil
+	}
+	keys := m.keys()
+	errs := make([]error, 0, len(keys))
+	for _, k := range keys {
+		errs = append(errs, m.Errors[k])
+	}
+	return errs
+}
+
+// Is - returns true if any of the individual errors matches target,
+// so that errors.Is inspects them with any Go version.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.Unwrap() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As - finds the first individual error, sorted by key, which matches
+// target and sets target to it, see errors.As.
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.Unwrap() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+
+	var empty MultiError
+	if empty.Any() || empty.ErrorOrNil() != nil {
+		t.Error("Expected empty MultiError to report no errors")
+	}
+	empty.Add("ignored", nil)
+	if empty.Any() {
+		t.Error("Expected nil error to be ignored")
+	}
+
+	var single MultiError
+	single.Add("a", errA)
+	if !single.Any() {
+		t.Error("Expected single MultiError to report errors")
+	}
+	if msg := single.Error(); msg != "a: a failed" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	var multi MultiError
+	multi.Add("b", errB)
+	multi.Add("a", errA)
+	if msg := multi.Error(); msg != "2 operations failed: a: a failed; b: b failed" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+	err := multi.ErrorOrNil()
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Error("Expected errors.Is to match wrapped errors")
+	}
+	if !multi.Is(errB) || multi.Is(errors.New("c failed")) {
+		t.Error("Expected Is to only match wrapped errors")
+	}
+
+	var resp ErrorResponse
+	multi.Add("c", fmt.Errorf("c failed: %w", ErrorResponse{Code: "AccessDenied"}))
+	if !multi.As(&resp) || resp.Code != "AccessDenied" {
+		t.Errorf("Expected As to find the wrapped error response, got %+v", resp)
+	}
+	if !errors.As(multi.ErrorOrNil(), &resp) {
+		t.Error("Expected errors.As to find the wrapped error response")
+	}
+}
@@ -18,6 +18,7 @@ package madmin
 
 import (
 	"context"
+	"math"
 	"math/rand"
 	"net/http"
 	"sync"
@@ -41,6 +42,44 @@ const DefaultRetryUnit = time.Second
 // this maximum time duration.
 const DefaultRetryCap = time.Second * 30
 
+// Backoff - computes exponentially increasing delays, for callers
+// retrying or polling in their own loops.
+type Backoff struct {
+	// Base is the delay of the first attempt, doubled on each attempt.
+	Base time.Duration
+	// Max caps the delay, no cap is applied when zero.
+	Max time.Duration
+	// Jitter randomizes the delay over the full backoff time, i.e. in
+	// between zero and the exponential delay.
+	Jitter bool
+}
+
+// Next returns the delay to wait before the given attempt, attempts
+// start from 0. See https://www.awsarchitectureblog.com/2015/03/backoff.html
+func (b Backoff) Next(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+	sleep := b.Base
+	for i := 0; i < attempt && sleep > 0; i++ {
+		if b.Max > 0 && sleep >= b.Max {
+			break
+		}
+		if sleep > math.MaxInt64/2 {
+			sleep = math.MaxInt64
+			break
+		}
+		sleep *= 2
+	}
+	if b.Max > 0 && sleep > b.Max {
+		sleep = b.Max
+	}
+	if b.Jitter {
+		sleep -= time.Duration(rand.Float64() * float64(sleep))
+	}
+	return sleep
+}
+
 // lockedRandSource provides protected rand source, implements rand.Source interface.
 type lockedRandSource struct {
 	lk  sync.Mutex
@@ -80,10 +119,7 @@ func (adm AdminClient) newRetryTimer(ctx context.Context, maxRetry int, unit tim
 		}
 
 		//sleep = random_between(0, min(cap, base * 2 ** attempt))
-		sleep := unit * 1 << uint(attempt)
-		if sleep > cap {
-			sleep = cap
-		}
+		sleep := Backoff{Base: unit, Max: cap}.Next(attempt)
 		if jitter > NoJitter {
 			sleep -= time.Duration(adm.random.Float64() * float64(sleep) * jitter)
 		}
@@ -0,0 +1,57 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests Backoff growth, cap and jitter bounds.
+func TestBackoff(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Max: time.Second}
+	testCases := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{-1, 100 * time.Millisecond},
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{100, time.Second},
+	}
+	for i, testCase := range testCases {
+		if delay := b.Next(testCase.attempt); delay != testCase.delay {
+			t.Errorf("Test %d: Expected %v, got %v", i+1, testCase.delay, delay)
+		}
+	}
+
+	if delay := (Backoff{Base: time.Second}).Next(100); delay <= 0 {
+		t.Errorf("Expected uncapped delay to not overflow, got %v", delay)
+	}
+
+	b.Jitter = true
+	for attempt := 0; attempt < 10; attempt++ {
+		max := Backoff{Base: b.Base, Max: b.Max}.Next(attempt)
+		for i := 0; i < 100; i++ {
+			if delay := b.Next(attempt); delay < 0 || delay > max {
+				t.Fatalf("Attempt %d: Expected delay in [0, %v], got %v", attempt, max, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ServerTimeSkew - returns the difference between the server clock and
+// the local clock, positive when the server clock is ahead. It is
+// computed from the Date header of a lightweight request, hence has a
+// precision of about a second.
+//
+// The result can be used to correct server side timestamps such as
+// HealingDisk.Started or HealingDisk.LastUpdate. The local clock is
+// measured with time.Now rather than the clock set with SetClock, so
+// the skew can be used to build that clock.
+func (adm *AdminClient) ServerTimeSkew(ctx context.Context) (time.Duration, error) {
+	req, err := adm.newRequest(ctx, http.MethodHead, requestData{relPath: adminAPIPrefix + "/info"})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := time.Now()
+	resp, err := adm.do(req)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	closeResponse(resp)
+
+	// Any response, including errors, carries the server date.
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, ErrUnexpectedResponse("Server response has no Date header")
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, ErrUnexpectedResponse("Unable to parse server Date header: " + err.Error())
+	}
+
+	// Assume the server generated the response half way through
+	// the round trip.
+	local := sent.Add(received.Sub(sent) / 2)
+	return serverTime.Sub(local).Truncate(time.Second), nil
+}
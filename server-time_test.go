@@ -0,0 +1,40 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Tests ServerTimeSkew with a server clock one hour ahead.
+func TestServerTimeSkew(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	skew, err := adm.ServerTimeSkew(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skew < time.Hour-2*time.Second || skew > time.Hour+time.Second {
+		t.Errorf("Expected a skew of about an hour, got %v", skew)
+	}
+}
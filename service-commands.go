@@ -17,11 +17,15 @@
 package madmin
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -85,6 +89,84 @@ type ServiceTraceOpts struct {
 	OS         bool
 	OnlyErrors bool
 	Threshold  time.Duration
+
+	// Compress requests the server to gzip the trace stream, the
+	// stream is transparently decompressed. Servers not supporting
+	// compression reply with an uncompressed stream.
+	Compress bool
+
+	// Heartbeat, when set, makes the client emit a TraceHeartbeat
+	// trace whenever no trace was received from the server within
+	// the interval while the connection is established.
+	Heartbeat time.Duration
+}
+
+// Trace category names accepted by ServiceTraceOptsFromCategories.
+const (
+	TraceCategoryS3       = "s3"
+	TraceCategoryInternal = "internal"
+	TraceCategoryStorage  = "storage"
+	TraceCategoryOS       = "os"
+)
+
+// traceCategories maps a category name to the option it enables.
+var traceCategories = []struct {
+	name string
+	get  func(o *ServiceTraceOpts) *bool
+}{
+	{TraceCategoryS3, func(o *ServiceTraceOpts) *bool { return &o.S3 }},
+	{TraceCategoryInternal, func(o *ServiceTraceOpts) *bool { return &o.Internal }},
+	{TraceCategoryStorage, func(o *ServiceTraceOpts) *bool { return &o.Storage }},
+	{TraceCategoryOS, func(o *ServiceTraceOpts) *bool { return &o.OS }},
+}
+
+// ServiceTraceOptsFromCategories - returns trace options with the
+// given categories enabled, names are case insensitive. An error is
+// returned for unknown category names.
+func ServiceTraceOptsFromCategories(cats []string) (ServiceTraceOpts, error) {
+	var opts ServiceTraceOpts
+	for _, cat := range cats {
+		cat = strings.ToLower(strings.TrimSpace(cat))
+		found := false
+		for _, c := range traceCategories {
+			if c.name == cat {
+				*c.get(&opts) = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ServiceTraceOpts{}, ErrInvalidArgument(fmt.Sprintf("unknown trace category %q", cat))
+		}
+	}
+	return opts, nil
+}
+
+// Categories - returns the names of the trace categories enabled in o.
+func (o ServiceTraceOpts) Categories() []string {
+	var cats []string
+	for _, c := range traceCategories {
+		if o.All || *c.get(&o) {
+			cats = append(cats, c.name)
+		}
+	}
+	return cats
+}
+
+// traceTypes - returns the trace types of the categories enabled in
+// o, S3 and internal traces are both of type TraceHTTP.
+func (o ServiceTraceOpts) traceTypes() []TraceType {
+	var types []TraceType
+	if o.All || o.S3 || o.Internal {
+		types = append(types, TraceHTTP)
+	}
+	if o.All || o.OS {
+		types = append(types, TraceOS)
+	}
+	if o.All || o.Storage {
+		types = append(types, TraceStorage)
+	}
+	return types
 }
 
 // ServiceTrace - listen on http trace notifications.
@@ -111,6 +193,10 @@ func (adm AdminClient) ServiceTrace(ctx context.Context, opts ServiceTraceOpts)
 				relPath:     adminAPIPrefix + "/trace",
 				queryValues: urlValues,
 			}
+			if opts.Compress {
+				reqData.customHeaders = make(http.Header)
+				reqData.customHeaders.Set("Accept-Encoding", "gzip")
+			}
 			// Execute GET to call trace handler
 			resp, err := adm.executeMethod(ctx, http.MethodGet, reqData)
 			if err != nil {
@@ -124,21 +210,93 @@ func (adm AdminClient) ServiceTrace(ctx context.Context, opts ServiceTraceOpts)
 				return
 			}
 
-			dec := json.NewDecoder(resp.Body)
-			for {
-				var info TraceInfo
-				if err = dec.Decode(&info); err != nil {
-					break
-				}
-				select {
-				case <-ctx.Done():
+			var body io.Reader = resp.Body
+			if resp.Header.Get("Content-Encoding") == "gzip" {
+				gr, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					closeResponse(resp)
+					traceInfoCh <- ServiceTraceInfo{Err: err}
 					return
-				case traceInfoCh <- ServiceTraceInfo{Trace: info}:
 				}
+				body = gr
 			}
+
+			if !forwardTraces(ctx, json.NewDecoder(body), traceInfoCh, opts.Heartbeat, adm.Now) {
+				return
+			}
+			closeResponse(resp)
 		}
 	}(traceInfoCh)
 
 	// Returns the trace info channel, for caller to start reading from.
 	return traceInfoCh
 }
+
+// forwardTraces sends the traces decoded from dec to traceInfoCh until
+// decoding fails. When heartbeat is set a TraceHeartbeat trace is sent
+// whenever no trace was decoded within the interval. Traces are stamped
+// with the receive time returned by now. false is returned if ctx is
+// canceled.
+func forwardTraces(ctx context.Context, dec *json.Decoder, traceInfoCh chan<- ServiceTraceInfo, heartbeat time.Duration, now func() time.Time) bool {
+	if heartbeat <= 0 {
+		for {
+			var info TraceInfo
+			if err := dec.Decode(&info); err != nil {
+				return true
+			}
+			info.ReceivedAt = now()
+			select {
+			case <-ctx.Done():
+				return false
+			case traceInfoCh <- ServiceTraceInfo{Trace: info}:
+			}
+		}
+	}
+
+	decodedCh := make(chan TraceInfo)
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		defer close(decodedCh)
+		for {
+			var info TraceInfo
+			if err := dec.Decode(&info); err != nil {
+				return
+			}
+			info.ReceivedAt = now()
+			select {
+			case <-doneCh:
+				return
+			case decodedCh <- info:
+			}
+		}
+	}()
+
+	timer := time.NewTimer(heartbeat)
+	defer timer.Stop()
+	for {
+		var info TraceInfo
+		select {
+		case <-ctx.Done():
+			return false
+		case t, ok := <-decodedCh:
+			if !ok {
+				// Connection dropped, stop sending heartbeats.
+				return true
+			}
+			info = t
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+			t := now()
+			info = TraceInfo{TraceType: TraceHeartbeat, Time: t, ReceivedAt: t}
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case traceInfoCh <- ServiceTraceInfo{Trace: info}:
+		}
+		timer.Reset(heartbeat)
+	}
+}
@@ -0,0 +1,179 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests ServiceTraceOptsFromCategories and its reverse Categories.
+func TestServiceTraceOptsFromCategories(t *testing.T) {
+	testCases := []struct {
+		cats       []string
+		expected   ServiceTraceOpts
+		expectCats []string
+		expectErr  bool
+	}{
+		{cats: nil, expected: ServiceTraceOpts{}, expectCats: nil},
+		{cats: []string{"s3", "Storage", " os"}, expected: ServiceTraceOpts{S3: true, Storage: true, OS: true}, expectCats: []string{"s3", "storage", "os"}},
+		{cats: []string{"internal"}, expected: ServiceTraceOpts{Internal: true}, expectCats: []string{"internal"}},
+		{cats: []string{"s3", "bogus"}, expectErr: true},
+	}
+
+	for i, testCase := range testCases {
+		opts, err := ServiceTraceOptsFromCategories(testCase.cats)
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i+1, err)
+		}
+		if opts != testCase.expected {
+			t.Errorf("Test %d: expected %+v, got %+v", i+1, testCase.expected, opts)
+		}
+		if cats := opts.Categories(); !reflect.DeepEqual(cats, testCase.expectCats) {
+			t.Errorf("Test %d: expected categories %v, got %v", i+1, testCase.expectCats, cats)
+		}
+	}
+}
+
+// Tests ServiceTrace decoding a gzip encoded chunked stream.
+func TestServiceTraceCompressed(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Expected gzip to be accepted, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		enc := json.NewEncoder(gw)
+		for _, fn := range []string{"s3.GetObject", "s3.PutObject"} {
+			enc.Encode(TraceInfo{FuncName: fn})
+			gw.Flush()
+			w.(http.Flusher).Flush()
+		}
+		gw.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	traceCh := adm.ServiceTrace(ctx, ServiceTraceOpts{S3: true, Compress: true})
+	for _, expected := range []string{"s3.GetObject", "s3.PutObject"} {
+		info := <-traceCh
+		if info.Err != nil {
+			t.Fatal(info.Err)
+		}
+		if info.Trace.FuncName != expected {
+			t.Errorf("Expected %s, got %s", expected, info.Trace.FuncName)
+		}
+	}
+}
+
+// Tests ServiceTrace stamps traces with their receive time.
+func TestServiceTraceReceivedAt(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TraceInfo{FuncName: "s3.GetObject", Time: time.Now().Add(-time.Hour)})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	info := <-adm.ServiceTrace(ctx, ServiceTraceOpts{S3: true})
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+	if info.Trace.ReceivedAt.Before(start) || time.Since(info.Trace.ReceivedAt) > time.Minute {
+		t.Errorf("Unexpected receive time %v", info.Trace.ReceivedAt)
+	}
+
+	received := time.Unix(1000, 0)
+	adm.SetClock(func() time.Time { return received })
+	info = <-adm.ServiceTrace(ctx, ServiceTraceOpts{S3: true})
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+	if !info.Trace.ReceivedAt.Equal(received) {
+		t.Errorf("Expected receive time %v from the client clock, got %v", received, info.Trace.ReceivedAt)
+	}
+
+	b, err := json.Marshal(info.Trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TraceInfo
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.ReceivedAt.IsZero() {
+		t.Error("Expected receive time to not be serialized")
+	}
+}
+
+// Tests heartbeats are sent during silence and stop on disconnect.
+func TestServiceTraceHeartbeat(t *testing.T) {
+	var requests int
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.Encode(TraceInfo{FuncName: "first"})
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		enc.Encode(TraceInfo{FuncName: "second"})
+	})
+
+	var (
+		funcs      []string
+		heartbeats int
+		err        error
+	)
+	for info := range adm.ServiceTrace(context.Background(), ServiceTraceOpts{S3: true, Heartbeat: 30 * time.Millisecond}) {
+		switch {
+		case info.Err != nil:
+			err = info.Err
+		case info.Trace.TraceType == TraceHeartbeat:
+			if len(funcs) != 1 {
+				t.Errorf("Unexpected heartbeat after %v", funcs)
+			}
+			heartbeats++
+		default:
+			funcs = append(funcs, info.Trace.FuncName)
+		}
+	}
+	if !reflect.DeepEqual(funcs, []string{"first", "second"}) {
+		t.Errorf("Unexpected traces %v", funcs)
+	}
+	if heartbeats < 2 {
+		t.Errorf("Expected heartbeats during silence, got %d", heartbeats)
+	}
+	if err == nil {
+		t.Error("Expected error after disconnect")
+	}
+}
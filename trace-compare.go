@@ -0,0 +1,158 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"sort"
+	"time"
+)
+
+// TraceDiffInterval is the interval at which CompareTraces emits
+// intermediate aggregates.
+var TraceDiffInterval = 5 * time.Second
+
+// TraceDiff - aggregated latency comparison of a single function
+// between two trace sessions.
+type TraceDiff struct {
+	Func   string        `json:"func"`
+	CountA int           `json:"countA"`
+	CountB int           `json:"countB"`
+	P50A   time.Duration `json:"p50A"`
+	P50B   time.Duration `json:"p50B"`
+}
+
+// Latency returns the duration recorded for the trace, according
+// to its trace type.
+func (t TraceInfo) Latency() time.Duration {
+	switch t.TraceType {
+	case TraceStorage:
+		return t.StorageStats.Duration
+	case TraceOS:
+		return t.OSStats.Duration
+	}
+	return t.CallStats.Latency
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the sorted
+// durations in d.
+func percentile(d []time.Duration, p float64) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	idx := int(float64(len(d))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(d) {
+		idx = len(d) - 1
+	}
+	return d[idx]
+}
+
+// traceLatencies holds sampled latencies of both sessions for a
+// function.
+type traceLatencies struct {
+	a, b    latencyReservoir
+	updated bool
+}
+
+// sortedSamples returns a sorted copy of the samples of r.
+func sortedSamples(r *latencyReservoir) []time.Duration {
+	d := append([]time.Duration(nil), r.samples...)
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return d
+}
+
+func (l *traceLatencies) diff(fn string) TraceDiff {
+	return TraceDiff{
+		Func:   fn,
+		CountA: int(l.a.seen),
+		CountB: int(l.b.seen),
+		P50A:   percentile(sortedSamples(&l.a), 0.5),
+		P50B:   percentile(sortedSamples(&l.b), 0.5),
+	}
+}
+
+// CompareTraces - reads trace events from two sessions a and b, buckets
+// them by function name and periodically emits the aggregates of the
+// functions that received new events. Once both sessions have ended a
+// final summary for all functions is sent, ordered by function name,
+// and the returned channel is closed. Heartbeats are skipped. At most
+// 1024 latencies are kept per function and session, the medians are
+// approximated from a uniform sample of the traces once more were
+// received.
+func CompareTraces(a, b <-chan TraceInfo) <-chan TraceDiff {
+	diffCh := make(chan TraceDiff)
+	go func() {
+		defer close(diffCh)
+
+		funcs := make(map[string]*traceLatencies)
+		get := func(fn string) *traceLatencies {
+			l, ok := funcs[fn]
+			if !ok {
+				l = &traceLatencies{}
+				funcs[fn] = l
+			}
+			l.updated = true
+			return l
+		}
+		flush := func(all bool) {
+			names := make([]string, 0, len(funcs))
+			for fn, l := range funcs {
+				if all || l.updated {
+					names = append(names, fn)
+				}
+			}
+			sort.Strings(names)
+			for _, fn := range names {
+				l := funcs[fn]
+				l.updated = false
+				diffCh <- l.diff(fn)
+			}
+		}
+
+		ticker := time.NewTicker(TraceDiffInterval)
+		defer ticker.Stop()
+
+		for a != nil || b != nil {
+			select {
+			case t, ok := <-a:
+				if !ok {
+					a = nil
+					continue
+				}
+				if t.TraceType == TraceHeartbeat {
+					continue
+				}
+				get(t.FuncName).a.add(t.Latency())
+			case t, ok := <-b:
+				if !ok {
+					b = nil
+					continue
+				}
+				if t.TraceType == TraceHeartbeat {
+					continue
+				}
+				get(t.FuncName).b.add(t.Latency())
+			case <-ticker.C:
+				flush(false)
+			}
+		}
+		flush(true)
+	}()
+	return diffCh
+}
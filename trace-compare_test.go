@@ -0,0 +1,96 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"testing"
+	"time"
+)
+
+func sendTraces(fn string, latencies ...time.Duration) <-chan TraceInfo {
+	ch := make(chan TraceInfo)
+	go func() {
+		defer close(ch)
+		for _, l := range latencies {
+			ch <- TraceInfo{FuncName: fn, CallStats: TraceCallStats{Latency: l}}
+		}
+	}()
+	return ch
+}
+
+// Tests CompareTraces final summary with sessions of different lengths.
+func TestCompareTraces(t *testing.T) {
+	a := sendTraces("s3.GetObject", time.Millisecond, 3*time.Millisecond, 2*time.Millisecond)
+	b := sendTraces("s3.GetObject", 10*time.Millisecond)
+
+	var last TraceDiff
+	for d := range CompareTraces(a, b) {
+		last = d
+	}
+	expected := TraceDiff{
+		Func:   "s3.GetObject",
+		CountA: 3,
+		CountB: 1,
+		P50A:   2 * time.Millisecond,
+		P50B:   10 * time.Millisecond,
+	}
+	if last != expected {
+		t.Errorf("Expected %+v, got %+v", expected, last)
+	}
+}
+
+// Tests CompareTraces skips heartbeats and bounds the kept latencies.
+func TestCompareTracesBounded(t *testing.T) {
+	const n = 5000
+	a := make(chan TraceInfo)
+	go func() {
+		defer close(a)
+		a <- TraceInfo{TraceType: TraceHeartbeat, FuncName: "heartbeat"}
+		for i := 0; i < n; i++ {
+			a <- TraceInfo{FuncName: "s3.GetObject", CallStats: TraceCallStats{Latency: time.Millisecond}}
+		}
+	}()
+	b := sendTraces("s3.GetObject", 2*time.Millisecond)
+
+	var diffs []TraceDiff
+	for d := range CompareTraces(a, b) {
+		diffs = append(diffs, d)
+	}
+	for _, d := range diffs {
+		if d.Func == "heartbeat" {
+			t.Fatalf("Expected heartbeats to be skipped, got %+v", d)
+		}
+	}
+	expected := TraceDiff{
+		Func:   "s3.GetObject",
+		CountA: n,
+		CountB: 1,
+		P50A:   time.Millisecond,
+		P50B:   2 * time.Millisecond,
+	}
+	if last := diffs[len(diffs)-1]; last != expected {
+		t.Errorf("Expected %+v, got %+v", expected, last)
+	}
+
+	var l traceLatencies
+	for i := 0; i < n; i++ {
+		l.a.add(time.Duration(i))
+	}
+	if len(l.a.samples) != traceLatencyReservoirSize {
+		t.Errorf("Expected %d samples, got %d", traceLatencyReservoirSize, len(l.a.samples))
+	}
+}
@@ -0,0 +1,90 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// traceLatencyReservoirSize is the maximum number of latencies kept
+// per function by TraceLatencyAggregator.
+const traceLatencyReservoirSize = 1024
+
+// latencyReservoir - uniform sample of the latencies of a function.
+type latencyReservoir struct {
+	samples []time.Duration
+	seen    int64
+}
+
+// add adds d to the sample, once the sample is full each latency
+// replaces a random sampled one with probability size/seen so the
+// sample stays uniform.
+func (r *latencyReservoir) add(d time.Duration) {
+	r.seen++
+	if len(r.samples) < traceLatencyReservoirSize {
+		r.samples = append(r.samples, d)
+		return
+	}
+	if i := rand.Int63n(r.seen); i < traceLatencyReservoirSize {
+		r.samples[i] = d
+	}
+}
+
+// TraceLatencyAggregator - aggregates the latencies of traces per
+// function to compute their percentiles. At most 1024 latencies are
+// kept per function, percentiles are approximated from a uniform
+// sample of the traces once more have been added. It is safe for
+// concurrent use, its zero value is ready to use.
+type TraceLatencyAggregator struct {
+	mu    sync.Mutex
+	funcs map[string]*latencyReservoir
+}
+
+// Add adds the latency of t to its function.
+func (a *TraceLatencyAggregator) Add(t TraceInfo) {
+	if t.TraceType == TraceHeartbeat {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.funcs == nil {
+		a.funcs = make(map[string]*latencyReservoir)
+	}
+	r, ok := a.funcs[t.FuncName]
+	if !ok {
+		r = &latencyReservoir{}
+		a.funcs[t.FuncName] = r
+	}
+	r.add(t.Latency())
+}
+
+// Percentiles returns the 50th, 95th and 99th percentiles of the
+// latencies of fn, zero when no trace of fn was added.
+func (a *TraceLatencyAggregator) Percentiles(fn string) (p50, p95, p99 time.Duration) {
+	a.mu.Lock()
+	var samples []time.Duration
+	if r, ok := a.funcs[fn]; ok {
+		samples = append(samples, r.samples...)
+	}
+	a.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return percentile(samples, 0.5), percentile(samples, 0.95), percentile(samples, 0.99)
+}
@@ -0,0 +1,65 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests TraceLatencyAggregator percentiles of a uniform distribution
+// fed concurrently with reads.
+func TestTraceLatencyAggregator(t *testing.T) {
+	var agg TraceLatencyAggregator
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Latencies from 1ms to 10000ms, split across the workers.
+			for i := w + 1; i <= 10000; i += 4 {
+				agg.Add(TraceInfo{FuncName: "s3.GetObject", CallStats: TraceCallStats{Latency: time.Duration(i) * time.Millisecond}})
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			agg.Percentiles("s3.GetObject")
+		}
+	}()
+	wg.Wait()
+
+	p50, p95, p99 := agg.Percentiles("s3.GetObject")
+	approx := func(name string, got, want time.Duration) {
+		// The sample of 1024 latencies is well within 10% of the
+		// distribution.
+		if diff := got - want; diff < -time.Second || diff > time.Second {
+			t.Errorf("Expected %s around %v, got %v", name, want, got)
+		}
+	}
+	approx("p50", p50, 5000*time.Millisecond)
+	approx("p95", p95, 9500*time.Millisecond)
+	approx("p99", p99, 9900*time.Millisecond)
+
+	if p50, p95, p99 = agg.Percentiles("s3.PutObject"); p50 != 0 || p95 != 0 || p99 != 0 {
+		t.Error("Expected zero percentiles for unknown function")
+	}
+}
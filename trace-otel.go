@@ -0,0 +1,88 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"net/http"
+	"time"
+)
+
+// SpanData - a trace mapped to an OpenTelemetry span, it only holds
+// plain values and can be converted to the span type of any OTel SDK.
+// Attribute names follow the OTel semantic conventions, attribute
+// values are either strings or int64.
+type SpanData struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+
+	// Error is true when the traced call failed, i.e. the span
+	// status must be set to error.
+	Error bool `json:"error,omitempty"`
+
+	Resource   map[string]string      `json:"resource"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// ToOTelSpan maps the trace to an OpenTelemetry span: the function name
+// is the span name, the node name is the "host.name" resource attribute,
+// the request and response of HTTP traces are mapped to "http." span
+// attributes and the path of storage and OS traces to "minio.path".
+func (t TraceInfo) ToOTelSpan() SpanData {
+	span := SpanData{
+		Name:      t.FuncName,
+		StartTime: t.Time,
+		Resource: map[string]string{
+			"service.name": "minio",
+			"host.name":    t.NodeName,
+		},
+		Attributes: make(map[string]interface{}),
+	}
+
+	switch t.TraceType {
+	case TraceStorage:
+		span.EndTime = span.StartTime.Add(t.StorageStats.Duration)
+		span.Attributes["minio.path"] = t.StorageStats.Path
+	case TraceOS:
+		span.EndTime = span.StartTime.Add(t.OSStats.Duration)
+		span.Attributes["minio.path"] = t.OSStats.Path
+	default:
+		if !t.ReqInfo.Time.IsZero() {
+			span.StartTime = t.ReqInfo.Time
+		}
+		span.EndTime = t.RespInfo.Time
+		if span.EndTime.IsZero() {
+			span.EndTime = span.StartTime.Add(t.CallStats.Latency)
+		}
+
+		target := t.ReqInfo.Path
+		if t.ReqInfo.RawQuery != "" {
+			target += "?" + t.ReqInfo.RawQuery
+		}
+		span.Attributes["http.method"] = t.ReqInfo.Method
+		span.Attributes["http.target"] = target
+		span.Attributes["http.flavor"] = t.ReqInfo.Proto
+		span.Attributes["http.client_ip"] = t.ReqInfo.Client
+		span.Attributes["http.request_content_length"] = int64(t.CallStats.InputBytes)
+		span.Attributes["http.response_content_length"] = int64(t.CallStats.OutputBytes)
+		if t.RespInfo.StatusCode != 0 {
+			span.Attributes["http.status_code"] = int64(t.RespInfo.StatusCode)
+			span.Error = t.RespInfo.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	return span
+}
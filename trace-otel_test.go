@@ -0,0 +1,71 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests ToOTelSpan maps the fields of an HTTP trace.
+func TestTraceInfoToOTelSpan(t *testing.T) {
+	start := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	trace := TraceInfo{
+		TraceType: TraceHTTP,
+		NodeName:  "server1:9000",
+		FuncName:  "s3.GetObject",
+		Time:      start,
+		ReqInfo: TraceRequestInfo{
+			Time:     start,
+			Proto:    "HTTP/1.1",
+			Method:   "GET",
+			Path:     "/bucket/object",
+			RawQuery: "versionId=v1",
+			Client:   "10.0.0.1",
+		},
+		RespInfo:  TraceResponseInfo{Time: start.Add(20 * time.Millisecond), StatusCode: 503},
+		CallStats: TraceCallStats{InputBytes: 10, OutputBytes: 100, Latency: 20 * time.Millisecond},
+	}
+
+	expected := SpanData{
+		Name:      "s3.GetObject",
+		StartTime: start,
+		EndTime:   start.Add(20 * time.Millisecond),
+		Error:     true,
+		Resource:  map[string]string{"service.name": "minio", "host.name": "server1:9000"},
+		Attributes: map[string]interface{}{
+			"http.method":                  "GET",
+			"http.target":                  "/bucket/object?versionId=v1",
+			"http.flavor":                  "HTTP/1.1",
+			"http.client_ip":               "10.0.0.1",
+			"http.request_content_length":  int64(10),
+			"http.response_content_length": int64(100),
+			"http.status_code":             int64(503),
+		},
+	}
+	if span := trace.ToOTelSpan(); !reflect.DeepEqual(span, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, span)
+	}
+
+	storage := TraceInfo{TraceType: TraceStorage, FuncName: "storage.ReadAll", Time: start,
+		StorageStats: TraceStorageStats{Path: "/disk1/bucket/object", Duration: time.Millisecond}}
+	span := storage.ToOTelSpan()
+	if !span.EndTime.Equal(start.Add(time.Millisecond)) || span.Attributes["minio.path"] != "/disk1/bucket/object" {
+		t.Errorf("Unexpected storage span %+v", span)
+	}
+}
@@ -0,0 +1,252 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"sync"
+)
+
+// CountTrace - passes the trace events of in through unchanged while
+// counting them per trace type. The returned function returns a
+// snapshot of the counters and may be called at any time, also after
+// in has been closed. The returned channel is closed when in is closed.
+func CountTrace(in <-chan TraceInfo) (<-chan TraceInfo, func() map[TraceType]uint64) {
+	var (
+		mu     sync.Mutex
+		counts = make(map[TraceType]uint64)
+	)
+	out := make(chan TraceInfo)
+	go func() {
+		defer close(out)
+		for t := range in {
+			mu.Lock()
+			counts[t.TraceType]++
+			mu.Unlock()
+			out <- t
+		}
+	}()
+
+	snapshot := func() map[TraceType]uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		s := make(map[TraceType]uint64, len(counts))
+		for k, v := range counts {
+			s[k] = v
+		}
+		return s
+	}
+	return out, snapshot
+}
+
+// TraceRing - holds the most recent trace events of a trace
+// subscription, see AdminClient.TraceRingBuffer.
+type TraceRing struct {
+	mu     sync.Mutex
+	traces []TraceInfo
+	next   int
+	full   bool
+	err    error
+}
+
+func (r *TraceRing) add(t TraceInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces[r.next] = t
+	r.next++
+	if r.next == len(r.traces) {
+		r.next, r.full = 0, true
+	}
+}
+
+// Snapshot returns the trace events held by the ring, oldest first.
+func (r *TraceRing) Snapshot() []TraceInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]TraceInfo{}, r.traces[:r.next]...)
+	}
+	snapshot := make([]TraceInfo, 0, len(r.traces))
+	snapshot = append(snapshot, r.traces[r.next:]...)
+	return append(snapshot, r.traces[:r.next]...)
+}
+
+// Err returns the last error reported by the trace subscription.
+func (r *TraceRing) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// TraceRingBuffer - subscribes to the trace events selected by opts in
+// the background and keeps the last size events, at least one, in the
+// returned ring. Heartbeat traces are not kept. The subscription runs
+// until ctx is canceled or the returned function is called, which
+// waits for the subscription to end.
+func (adm *AdminClient) TraceRingBuffer(ctx context.Context, opts ServiceTraceOpts, size int) (*TraceRing, func()) {
+	if size < 1 {
+		size = 1
+	}
+	ring := &TraceRing{traces: make([]TraceInfo, size)}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for info := range adm.ServiceTrace(ctx, opts) {
+			if info.Err != nil {
+				ring.mu.Lock()
+				ring.err = info.Err
+				ring.mu.Unlock()
+				continue
+			}
+			if info.Trace.TraceType != TraceHeartbeat {
+				ring.add(info.Trace)
+			}
+		}
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	return ring, stop
+}
+
+// ServiceTraceByCategory - subscribes to the traces enabled in opts
+// and demultiplexes them into one channel per trace type, S3 and
+// internal traces are both sent on the TraceHTTP channel. Heartbeats
+// are dropped. The channels are closed when the trace stream ends,
+// either because of an error or once ctx is canceled or the returned
+// stop function is called. The error channel then receives the error
+// which ended the stream, if any, before being closed. A stream ended
+// by canceling ctx or calling stop reports no error. stop waits for
+// the stream to end and may be called more than once.
+//
+// All the returned trace channels must be read from, a channel which
+// is not read blocks the traces of the other channels.
+func (adm *AdminClient) ServiceTraceByCategory(ctx context.Context, opts ServiceTraceOpts) (map[TraceType]<-chan TraceInfo, <-chan error, func()) {
+	chans := make(map[TraceType]chan TraceInfo)
+	out := make(map[TraceType]<-chan TraceInfo)
+	for _, typ := range opts.traceTypes() {
+		ch := make(chan TraceInfo)
+		chans[typ] = ch
+		out[typ] = ch
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+			if err != nil {
+				errCh <- err
+			}
+			close(errCh)
+		}()
+		// Keep draining the trace stream after ctx is canceled, so
+		// that ServiceTrace is never blocked and ends.
+		for info := range adm.ServiceTrace(ctx, opts) {
+			if info.Err != nil {
+				if ctx.Err() == nil && err == nil {
+					err = info.Err
+				}
+				continue
+			}
+			ch, ok := chans[info.Trace.TraceType]
+			if !ok {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case ch <- info.Trace:
+			}
+		}
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	return out, errCh, stop
+}
+
+// traceDrainBufferSize is the number of trace events buffered by
+// ServiceTraceWithDrain.
+const traceDrainBufferSize = 100
+
+// ServiceTraceWithDrain - subscribes to the traces enabled in opts, the
+// returned channel buffers up to traceDrainBufferSize events. Once ctx
+// is canceled the returned drain function waits for the trace stream
+// to end and returns the events not consumed from the channel yet, so
+// that they can be flushed instead of being lost. Events which did not
+// fit in the buffer at cancellation are kept for the drain as well.
+// The channel is closed when the stream ends, either once ctx is
+// canceled or because of an error, which drain returns along with the
+// events. A stream ended by canceling ctx reports no error.
+//
+// drain blocks until the stream ends, e.g. after ctx is canceled. Only
+// the first call returns events and the error, later calls return
+// nothing.
+func (adm *AdminClient) ServiceTraceWithDrain(ctx context.Context, opts ServiceTraceOpts) (<-chan TraceInfo, func() ([]TraceInfo, error)) {
+	traceCh := make(chan TraceInfo, traceDrainBufferSize)
+	var (
+		pending []TraceInfo
+		err     error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(traceCh)
+		for info := range adm.ServiceTrace(ctx, opts) {
+			if info.Err != nil {
+				if ctx.Err() == nil && err == nil {
+					err = info.Err
+				}
+				continue
+			}
+			select {
+			case traceCh <- info.Trace:
+			case <-ctx.Done():
+				pending = append(pending, info.Trace)
+			}
+		}
+	}()
+
+	var once sync.Once
+	drain := func() ([]TraceInfo, error) {
+		var (
+			traces   []TraceInfo
+			drainErr error
+		)
+		once.Do(func() {
+			<-done
+			for trace := range traceCh {
+				traces = append(traces, trace)
+			}
+			traces = append(traces, pending...)
+			drainErr = err
+		})
+		return traces, drainErr
+	}
+	return traceCh, drain
+}
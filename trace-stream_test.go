@@ -0,0 +1,216 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Tests CountTrace counters over a mixed stream.
+func TestCountTrace(t *testing.T) {
+	in := make(chan TraceInfo)
+	go func() {
+		defer close(in)
+		for _, typ := range []TraceType{TraceHTTP, TraceStorage, TraceHTTP, TraceOS, TraceStorage, TraceHTTP} {
+			in <- TraceInfo{TraceType: typ}
+		}
+	}()
+
+	out, counts := CountTrace(in)
+	var n int
+	for range out {
+		n++
+	}
+	if n != 6 {
+		t.Errorf("Expected 6 events, got %d", n)
+	}
+	expected := map[TraceType]uint64{TraceHTTP: 3, TraceStorage: 2, TraceOS: 1}
+	if c := counts(); !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %v, got %v", expected, c)
+	}
+}
+
+// Tests TraceRingBuffer only keeps the last events.
+func TestTraceRingBuffer(t *testing.T) {
+	var requests int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			enc := json.NewEncoder(w)
+			for i := 0; i < 10; i++ {
+				enc.Encode(TraceInfo{FuncName: "f" + strconv.Itoa(i)})
+			}
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	})
+
+	ring, stop := adm.TraceRingBuffer(context.Background(), ServiceTraceOpts{S3: true}, 3)
+	defer stop()
+
+	expected := []string{"f7", "f8", "f9"}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var names []string
+		for _, trace := range ring.Snapshot() {
+			names = append(names, trace.FuncName)
+		}
+		if reflect.DeepEqual(names, expected) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %v, got %v", expected, names)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests ServiceTraceByCategory sends each trace on the channel of its
+// type and drops the types which were not requested.
+func TestServiceTraceByCategory(t *testing.T) {
+	var requests int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			enc := json.NewEncoder(w)
+			enc.Encode(TraceInfo{TraceType: TraceHTTP, FuncName: "s3.GetObject"})
+			enc.Encode(TraceInfo{TraceType: TraceOS, FuncName: "os.OpenFile"})
+			enc.Encode(TraceInfo{TraceType: TraceStorage, FuncName: "storage.ReadAll"})
+			enc.Encode(TraceInfo{TraceType: TraceHTTP, FuncName: "s3.PutObject"})
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	})
+
+	chans, errCh, stop := adm.ServiceTraceByCategory(context.Background(), ServiceTraceOpts{S3: true, Storage: true})
+	if len(chans) != 2 || chans[TraceOS] != nil {
+		t.Fatalf("Expected HTTP and storage channels, got %v", chans)
+	}
+
+	var httpTraces, storageTraces []string
+	timeout := time.After(5 * time.Second)
+	for len(httpTraces)+len(storageTraces) < 3 {
+		select {
+		case trace := <-chans[TraceHTTP]:
+			httpTraces = append(httpTraces, trace.FuncName)
+		case trace := <-chans[TraceStorage]:
+			storageTraces = append(storageTraces, trace.FuncName)
+		case <-timeout:
+			t.Fatalf("Timed out, got %v and %v", httpTraces, storageTraces)
+		}
+	}
+	stop()
+
+	if !reflect.DeepEqual(httpTraces, []string{"s3.GetObject", "s3.PutObject"}) {
+		t.Errorf("Unexpected HTTP traces %v", httpTraces)
+	}
+	if !reflect.DeepEqual(storageTraces, []string{"storage.ReadAll"}) {
+		t.Errorf("Unexpected storage traces %v", storageTraces)
+	}
+	for typ, ch := range chans {
+		if _, ok := <-ch; ok {
+			t.Errorf("Expected the %v channel to be closed", typ)
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected no error after stop, got %v", err)
+	}
+}
+
+// Tests ServiceTraceByCategory reports the error ending the stream.
+func TestServiceTraceByCategoryError(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	chans, errCh, stop := adm.ServiceTraceByCategory(context.Background(), ServiceTraceOpts{S3: true})
+	defer stop()
+	for range chans[TraceHTTP] {
+		t.Error("Expected no trace")
+	}
+	if err := <-errCh; err == nil {
+		t.Error("Expected the error of the server")
+	}
+}
+
+// Tests the events buffered when the trace is canceled are returned by
+// the drain function.
+func TestServiceTraceWithDrain(t *testing.T) {
+	var requests int32
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			enc := json.NewEncoder(w)
+			for i := 0; i < 10; i++ {
+				enc.Encode(TraceInfo{FuncName: "f" + strconv.Itoa(i)})
+			}
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	traceCh, drain := adm.ServiceTraceWithDrain(ctx, ServiceTraceOpts{S3: true})
+	for i := 0; i < 3; i++ {
+		if trace := <-traceCh; trace.FuncName != "f"+strconv.Itoa(i) {
+			t.Fatalf("Expected f%d, got %s", i, trace.FuncName)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(traceCh) < 7 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 7 buffered events, got %d", len(traceCh))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	traces, err := drain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, trace := range traces {
+		names = append(names, trace.FuncName)
+	}
+	expected := []string{"f3", "f4", "f5", "f6", "f7", "f8", "f9"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+	if traces, err = drain(); traces != nil || err != nil {
+		t.Errorf("Expected nothing on the second drain, got %v, %v", traces, err)
+	}
+}
+
+// Tests the drain function reports the error ending the stream.
+func TestServiceTraceWithDrainError(t *testing.T) {
+	adm := newTestAdminClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	traceCh, drain := adm.ServiceTraceWithDrain(context.Background(), ServiceTraceOpts{S3: true})
+	for range traceCh {
+		t.Error("Expected no trace")
+	}
+	if _, err := drain(); err == nil {
+		t.Error("Expected the error of the server")
+	}
+}
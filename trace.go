@@ -33,6 +33,11 @@ const (
 	TraceStorage
 )
 
+// TraceHeartbeat is the type of the synthetic traces emitted by the
+// client when ServiceTraceOpts.Heartbeat is set, it is never sent
+// by the server.
+const TraceHeartbeat TraceType = -1
+
 // TraceInfo - represents a trace record, additionally
 // also reports errors if any while listening on trace.
 type TraceInfo struct {
@@ -48,6 +53,10 @@ type TraceInfo struct {
 
 	StorageStats TraceStorageStats `json:"storageStats"`
 	OSStats      TraceOSStats      `json:"osStats"`
+
+	// ReceivedAt is the client time at which the trace was read
+	// from the server, it is never serialized.
+	ReceivedAt time.Time `json:"-"`
 }
 
 // TraceStorageStats statistics on MinIO Storage layer calls
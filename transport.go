@@ -17,6 +17,7 @@
 package madmin
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
@@ -59,3 +60,15 @@ var DefaultTransport = func(secure bool) http.RoundTripper {
 	}
 	return tr
 }
+
+// unixSocketTransport - returns the default transport, dialing the
+// unix socket at socketPath for all the requests.
+func unixSocketTransport(socketPath string) http.RoundTripper {
+	tr := DefaultTransport(false).(*http.Transport)
+	tr.Proxy = nil
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socketPath)
+	}
+	return tr
+}
@@ -17,12 +17,15 @@
 package madmin
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/minio/minio-go/v7/pkg/s3utils"
@@ -41,6 +44,33 @@ func jsonDecoder(body io.Reader, v interface{}) error {
 	return d.Decode(v)
 }
 
+// jsonUint64 decodes an uint64 encoded either as a JSON number or as
+// a JSON string, as done by producers avoiding the precision limits
+// of JavaScript numbers.
+type jsonUint64 uint64
+
+// UnmarshalJSON - decodes a numeric or string encoded uint64.
+func (u *jsonUint64) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*u = jsonUint64(v)
+		return nil
+	}
+	var v uint64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*u = jsonUint64(v)
+	return nil
+}
+
 // getEndpointURL - construct a new endpoint.
 func getEndpointURL(endpoint string, secure bool) (*url.URL, error) {
 	if strings.Contains(endpoint, ":") {
@@ -122,3 +152,21 @@ func closeResponse(resp *http.Response) {
 		resp.Body.Close()
 	}
 }
+
+// decodeJSON decodes data into v, when strict is true fields of data
+// unknown to v are reported with ErrUnexpectedResponse.
+func decodeJSON(data []byte, v interface{}, strict bool) error {
+	if !strict {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			return ErrUnexpectedResponse(fmt.Sprintf("Unknown field %s in response decoded into %T",
+				strings.TrimPrefix(err.Error(), "json: unknown field "), v))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//
+// MinIO Object Storage (c) 2021 MinIO, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package madmin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestAdminClient starts a test server with handler h and returns
+// an admin client pointing to it. The server is closed when the test
+// finishes.
+func newTestAdminClient(t *testing.T, h http.HandlerFunc) *AdminClient {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	adm, err := New(strings.TrimPrefix(srv.URL, "http://"), "minio", "minio123", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adm
+}